@@ -0,0 +1,73 @@
+package bombardier
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+)
+
+// AddrMap keeps track of requests sent to each of the addresses
+// connections were established to.
+type AddrMap struct {
+	mu sync.RWMutex
+	m  map[string]*addrStats
+}
+
+type addrStats struct {
+	count     uint64
+	latencies *uhist.Histogram
+}
+
+func NewAddrMap() *AddrMap {
+	am := new(AddrMap)
+	am.m = make(map[string]*addrStats)
+	return am
+}
+
+func (a *AddrMap) Add(addr string, usTaken uint64) {
+	a.mu.RLock()
+	s, ok := a.m[addr]
+	a.mu.RUnlock()
+	if !ok {
+		a.mu.Lock()
+		s, ok = a.m[addr]
+		if !ok {
+			s = &addrStats{latencies: uhist.Default()}
+			a.m[addr] = s
+		}
+		a.mu.Unlock()
+	}
+	atomic.AddUint64(&s.count, 1)
+	s.latencies.Increment(usTaken)
+}
+
+func (a *AddrMap) Get(addr string) uint64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	s := a.m[addr]
+	if s == nil {
+		return uint64(0)
+	}
+	return atomic.LoadUint64(&s.count)
+}
+
+type AddrWithStats struct {
+	addr string
+	*addrStats
+}
+
+// ByAddr returns statistics for all addresses sorted alphabetically.
+func (a *AddrMap) ByAddr() []AddrWithStats {
+	a.mu.RLock()
+	res := make([]AddrWithStats, 0, len(a.m))
+	for addr, s := range a.m {
+		res = append(res, AddrWithStats{addr, s})
+	}
+	a.mu.RUnlock()
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].addr < res[j].addr
+	})
+	return res
+}
@@ -0,0 +1,30 @@
+package bombardier
+
+import (
+	"testing"
+)
+
+func TestAddrMapAdd(t *testing.T) {
+	m := NewAddrMap()
+	m.Add("10.0.0.1:80", 100)
+	m.Add("10.0.0.1:80", 200)
+	m.Add("10.0.0.2:80", 300)
+	if c := m.Get("10.0.0.1:80"); c != 2 {
+		t.Error(c)
+	}
+	if c := m.Get("10.0.0.3:80"); c != 0 {
+		t.Error(c)
+	}
+	byAddr := m.ByAddr()
+	if len(byAddr) != 2 {
+		t.Fatalf("Expected 2 addresses, but got %v", len(byAddr))
+	}
+	if byAddr[0].addr != "10.0.0.1:80" || byAddr[0].count != 2 ||
+		byAddr[0].latencies.Count() != 2 {
+		t.Errorf("Unexpected stats for %v", byAddr[0].addr)
+	}
+	if byAddr[1].addr != "10.0.0.2:80" || byAddr[1].count != 1 ||
+		byAddr[1].latencies.Get(300) != 1 {
+		t.Errorf("Unexpected stats for %v", byAddr[1].addr)
+	}
+}
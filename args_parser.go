@@ -20,7 +20,14 @@ type ArgsParser interface {
 type KingpinParser struct {
 	app *kingpin.Application
 
-	url string
+	url         string
+	unixSocket  string
+	connectTo   ConnectToList
+	resolve     ResolveList
+	spreadAddrs bool
+	localAddrs  LocalAddrList
+	ipVersion   int
+	proxy       string
 
 	numReqs           *NullableUint64
 	duration          *NullableDuration
@@ -33,9 +40,45 @@ type KingpinParser struct {
 	method            string
 	body              string
 	bodyFilePath      string
+	form              FormFields
+	compressBody      string
+	acceptEncoding    string
+	decompress        bool
+	pipeline          uint64
+	maxConnRequests   uint64
+	maxConnAge        time.Duration
+	streamsPerConn    uint64
+	bandwidth         BandwidthLimit
+	connLatency       time.Duration
+	sampleResponses   uint64
+	sampleDir         string
+	traceLog          string
+	errorHistogram    bool
+	statusLatencies   bool
 	stream            bool
 	certPath          string
 	keyPath           string
+	caCertPath        string
+	sni               string
+	tlsMinVersion     string
+	tlsMaxVersion     string
+	ciphers           string
+	curves            string
+	alpn              string
+	tlsResumption     string
+	tlsKeyLog         string
+	basicAuth         string
+	bearerFile        string
+	hmacSHA256        string
+	awsSigV4          string
+	oauth2TokenURL    string
+	clientID          string
+	clientSecret      string
+	scope             string
+	cookies           string
+	scenarioPath      string
+	thinkTime         ThinkTime
+	pacing            time.Duration
 	rate              *NullableUint64
 	clientType        ClientTyp
 
@@ -94,16 +137,209 @@ func NewKingpinParser() ArgsParser {
 		Default("").
 		Short('f').
 		StringVar(&kparser.bodyFilePath)
+	app.Flag("form", "Form field to send, values starting with @ are "+
+		"paths of files to upload as multipart/form-data, forms without "+
+		"files are URL-encoded(can be repeated)").
+		PlaceHolder("key=value|key=@path").
+		SetValue(&kparser.form)
+	app.Flag("compress-body", "Compress request body once before the "+
+		"test and send it with Content-Encoding header").
+		PlaceHolder("gzip|deflate|br|zstd").
+		EnumVar(&kparser.compressBody, encodingGzip, encodingDeflate,
+			encodingBrotli, encodingZstd)
+	app.Flag("accept-encoding", "Request compressed responses by "+
+		"sending this Accept-Encoding header").
+		PlaceHolder("<encodings>").
+		StringVar(&kparser.acceptEncoding)
+	app.Flag("decompress", "Decompress gzip, deflate, br and zstd "+
+		"response bodies and report decompressed byte counts and "+
+		"decompression errors").
+		BoolVar(&kparser.decompress)
 	app.Flag("stream", "Specify whether to stream body using "+
 		"chunked transfer encoding or to serve it from memory").
 		Short('s').
 		BoolVar(&kparser.stream)
-	app.Flag("cert", "Path to the Client's TLS Certificate").
+	app.Flag("cert", "Path to the Client's TLS Certificate, "+
+		"comma-separated list of paths or directory with certificate "+
+		"and key pairs, which are rotated across connections").
 		Default("").
 		StringVar(&kparser.certPath)
-	app.Flag("key", "Path to the Client's TLS Certificate Private Key").
+	app.Flag("key", "Path to the Client's TLS Certificate Private Key, "+
+		"comma-separated list of paths or directory with keys").
 		Default("").
 		StringVar(&kparser.keyPath)
+	app.Flag("cacert", "Path to the CA certificates bundle to verify "+
+		"server's certificate against").
+		PlaceHolder("<path>").
+		StringVar(&kparser.caCertPath)
+	app.Flag("sni", "Server name to send in TLS handshake and to "+
+		"verify server's certificate against").
+		PlaceHolder("<name>").
+		StringVar(&kparser.sni)
+	app.Flag("tls-min", "Minimum TLS version (1.0, 1.1, 1.2 or 1.3)").
+		PlaceHolder("<version>").
+		StringVar(&kparser.tlsMinVersion)
+	app.Flag("tls-max", "Maximum TLS version (1.0, 1.1, 1.2 or 1.3)").
+		PlaceHolder("<version>").
+		StringVar(&kparser.tlsMaxVersion)
+	app.Flag("ciphers", "Comma-separated list of TLS 1.0-1.2 cipher "+
+		"suites, i.e. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256").
+		PlaceHolder("<list>").
+		StringVar(&kparser.ciphers)
+	app.Flag("curves", "Comma-separated list of elliptic curves in "+
+		"order of preference (X25519, P256, P384, P521)").
+		PlaceHolder("<list>").
+		StringVar(&kparser.curves)
+	app.Flag("alpn", "Comma-separated list of application protocols "+
+		"to advertise in TLS handshake").
+		PlaceHolder("<list>").
+		StringVar(&kparser.alpn)
+	app.Flag("tls-resumption", "TLS session resumption mode: off (full "+
+		"handshake every time), tickets (each session ticket is used "+
+		"once) or cache (sessions are shared by all connections)").
+		PlaceHolder("off|tickets|cache").
+		EnumVar(&kparser.tlsResumption,
+			tlsResumptionOff, tlsResumptionTickets, tlsResumptionCache)
+	app.Flag("tls-keylog", "File to append TLS secrets to in NSS key "+
+		"log format, so that captured traffic can be decrypted").
+		PlaceHolder("<path>").
+		Envar("SSLKEYLOGFILE").
+		StringVar(&kparser.tlsKeyLog)
+	app.Flag("unix-socket", "Path to the Unix domain socket to connect to "+
+		"instead of the host from URL").
+		PlaceHolder("<path>").
+		StringVar(&kparser.unixSocket)
+	app.Flag("connect-to", "Connect to HOST2:PORT2 instead of "+
+		"HOST1:PORT1, either of hosts and ports may be empty(can be repeated)").
+		PlaceHolder("HOST1:PORT1:HOST2:PORT2").
+		SetValue(&kparser.connectTo)
+	app.Flag("resolve", "Use static addresses for HOST:PORT instead of "+
+		"system resolver(can be repeated)").
+		PlaceHolder("HOST:PORT:ADDR[,ADDR]...").
+		SetValue(&kparser.resolve)
+	app.Flag("spread-addrs", "Round-robin new connections across all "+
+		"addresses the target resolves to and report per-address statistics").
+		BoolVar(&kparser.spreadAddrs)
+	app.Flag("local-addr", "Local IP address to establish connections "+
+		"from, connections rotate across all of them(can be repeated)").
+		PlaceHolder("IP[,IP]...").
+		SetValue(&kparser.localAddrs)
+	app.Flag("ipv4", "Use IPv4 addresses only").
+		Short('4').
+		Action(func(*kingpin.ParseContext) error {
+			kparser.ipVersion = ipv4
+			return nil
+		}).
+		Bool()
+	app.Flag("ipv6", "Use IPv6 addresses only").
+		Short('6').
+		Action(func(*kingpin.ParseContext) error {
+			kparser.ipVersion = ipv6
+			return nil
+		}).
+		Bool()
+	app.Flag("proxy", "Proxy to send requests through, either "+
+		"http://[user:pass@]host:port or socks5://[user:pass@]host:port").
+		PlaceHolder("<url>").
+		StringVar(&kparser.proxy)
+	app.Flag("basic", "Credentials for HTTP Basic authentication").
+		PlaceHolder("user:pass").
+		StringVar(&kparser.basicAuth)
+	app.Flag("bearer-file", "File to read Bearer token from, the file "+
+		"is reread when it changes").
+		PlaceHolder("<path>").
+		StringVar(&kparser.bearerFile)
+	app.Flag("hmac-sha256", "Sign every request with HMAC-SHA256 of "+
+		"method, request URI, timestamp and body joined with newlines, "+
+		"timestamp is sent in X-Timestamp header").
+		PlaceHolder("key,header").
+		StringVar(&kparser.hmacSHA256)
+	app.Flag("aws-sigv4", "Sign every request with AWS Signature "+
+		"Version 4 using credentials from AWS_* environment variables").
+		PlaceHolder("region/service").
+		StringVar(&kparser.awsSigV4)
+	app.Flag("oauth2-token-url", "OAuth2 token endpoint to obtain "+
+		"access tokens from using client credentials grant, tokens are "+
+		"refreshed before they expire").
+		PlaceHolder("<url>").
+		StringVar(&kparser.oauth2TokenURL)
+	app.Flag("client-id", "OAuth2 client ID").
+		PlaceHolder("<id>").
+		StringVar(&kparser.clientID)
+	app.Flag("client-secret", "OAuth2 client secret").
+		PlaceHolder("<secret>").
+		StringVar(&kparser.clientSecret)
+	app.Flag("scope", "Space-separated list of OAuth2 scopes to request").
+		PlaceHolder("<scopes>").
+		StringVar(&kparser.scope)
+	app.Flag("cookies", "Keep cookies set by the server either in a "+
+		"single jar shared by all connections or in a separate jar for "+
+		"each worker, so that every worker keeps its own session").
+		PlaceHolder("shared|worker").
+		EnumVar(&kparser.cookies, cookiesShared, cookiesPerWorker)
+	app.Flag("scenario", "JSON file with an ordered list of steps every "+
+		"worker performs in each iteration, values extracted from "+
+		"responses can be referenced as ${name} by subsequent steps. "+
+		"Number of requests and rate apply to iterations then").
+		PlaceHolder("<file>").
+		StringVar(&kparser.scenarioPath)
+	app.Flag("think-time", "Time each worker waits after every request, "+
+		"either fixed or drawn from uniform, normal or exponential "+
+		"distribution").
+		PlaceHolder("DURATION|uniform:MIN,MAX|normal:MEAN,STDDEV|" +
+			"exponential:MEAN").
+		SetValue(&kparser.thinkTime)
+	app.Flag("pacing", "Minimum period of each request(or scenario "+
+		"iteration) per worker, including think time").
+		PlaceHolder("<duration>").
+		DurationVar(&kparser.pacing)
+	app.Flag("pipeline", "Pipeline up to N requests over each "+
+		"connection(fasthttp only)").
+		PlaceHolder("N").
+		Uint64Var(&kparser.pipeline)
+	app.Flag("max-conn-requests", "Reconnect after sending N requests "+
+		"over a connection, each worker owns a single connection then").
+		PlaceHolder("N").
+		Uint64Var(&kparser.maxConnRequests)
+	app.Flag("max-conn-age", "Reconnect once a connection gets older "+
+		"than the duration, each worker owns a single connection then").
+		PlaceHolder("<duration>").
+		DurationVar(&kparser.maxConnAge)
+	app.Flag("streams-per-connection", "Number of concurrent streams "+
+		"multiplexed over each connection, workers are pinned to the "+
+		"connections then(net/http v2.0 and https:// targets only)").
+		PlaceHolder("N").
+		Uint64Var(&kparser.streamsPerConn)
+	app.Flag("bandwidth-limit", "Limit rate of data sent and received "+
+		"over each connection, either the same for both directions or "+
+		"separate for upload and download, in B, KB, MB, GB or bps, "+
+		"kbps, mbps, gbps per second").
+		PlaceHolder("RATE|UP,DOWN").
+		SetValue(&kparser.bandwidth)
+	app.Flag("conn-latency", "Delay each read from and write to "+
+		"connections by the duration").
+		PlaceHolder("<duration>").
+		DurationVar(&kparser.connLatency)
+	app.Flag("sample-responses", "Keep the first N and a random sample "+
+		"of N other responses with each status code and occurrences of "+
+		"each error, bodies are truncated to 4KB").
+		PlaceHolder("N").
+		Uint64Var(&kparser.sampleResponses)
+	app.Flag("sample-dir", "Write sampled responses to files in the "+
+		"directory instead of embedding them into the output").
+		PlaceHolder("<dir>").
+		StringVar(&kparser.sampleDir)
+	app.Flag("trace-log", "Write each request, its status, latency, "+
+		"sizes and error to the file as a line of JSON, entries are "+
+		"dropped rather than slowing the requests down").
+		PlaceHolder("<file>").
+		StringVar(&kparser.traceLog)
+	app.Flag("error-histogram", "Count occurrences of each error "+
+		"during every second of the test").
+		BoolVar(&kparser.errorHistogram)
+	app.Flag("status-latencies", "Compute latencies of responses "+
+		"with each status code separately").
+		BoolVar(&kparser.statusLatencies)
 	app.Flag("insecure",
 		"Controls whether a Client verifies the server's certificate"+
 			" chain and host name").
@@ -208,23 +444,102 @@ func (k *KingpinParser) Parse(args []string) (Config, error) {
 			"unknown Format or invalid Format spec %q", k.formatSpec,
 		)
 	}
-	url, err := TryParseURL(k.url)
+	rawURL, unixSocket, err := SplitUnixSocketURL(k.url)
 	if err != nil {
 		return emptyConf, err
 	}
+	if unixSocket != "" && k.unixSocket != "" {
+		return emptyConf, errUnixSocketProvidedTwice
+	}
+	if unixSocket == "" {
+		unixSocket = k.unixSocket
+	}
+	url, err := TryParseURL(rawURL)
+	if err != nil {
+		return emptyConf, err
+	}
+	var (
+		connectTo  *ConnectToList
+		resolve    *ResolveList
+		localAddrs *LocalAddrList
+		thinkTime  *ThinkTime
+		form       *FormFields
+		bandwidth  *BandwidthLimit
+	)
+	if len(k.connectTo) > 0 {
+		connectTo = &k.connectTo
+	}
+	if len(k.resolve) > 0 {
+		resolve = &k.resolve
+	}
+	if len(k.localAddrs) > 0 {
+		localAddrs = &k.localAddrs
+	}
+	if len(k.form) > 0 {
+		form = &k.form
+	}
+	if k.thinkTime.dist != "" {
+		thinkTime = &k.thinkTime
+	}
+	if k.bandwidth.value != "" {
+		bandwidth = &k.bandwidth
+	}
 	return Config{
 		numConns:          k.numConns,
 		numReqs:           k.numReqs.val,
 		duration:          k.duration.val,
 		url:               url,
+		unixSocket:        unixSocket,
+		connectTo:         connectTo,
+		resolve:           resolve,
+		spreadAddrs:       k.spreadAddrs,
+		localAddrs:        localAddrs,
+		ipVersion:         k.ipVersion,
+		proxy:             k.proxy,
 		headers:           k.headers,
 		timeout:           k.timeout,
 		method:            k.method,
 		body:              k.body,
 		bodyFilePath:      k.bodyFilePath,
+		form:              form,
+		compressBody:      k.compressBody,
+		acceptEncoding:    k.acceptEncoding,
+		decompress:        k.decompress,
+		pipeline:          k.pipeline,
+		maxConnRequests:   k.maxConnRequests,
+		maxConnAge:        k.maxConnAge,
+		streamsPerConn:    k.streamsPerConn,
+		bandwidth:         bandwidth,
+		connLatency:       k.connLatency,
+		sampleResponses:   k.sampleResponses,
+		sampleDir:         k.sampleDir,
+		traceLog:          k.traceLog,
+		errorHistogram:    k.errorHistogram,
+		statusLatencies:   k.statusLatencies,
 		stream:            k.stream,
 		keyPath:           k.keyPath,
 		certPath:          k.certPath,
+		caCertPath:        k.caCertPath,
+		sni:               k.sni,
+		tlsMinVersion:     k.tlsMinVersion,
+		tlsMaxVersion:     k.tlsMaxVersion,
+		ciphers:           k.ciphers,
+		curves:            k.curves,
+		alpn:              k.alpn,
+		tlsResumption:     k.tlsResumption,
+		tlsKeyLog:         k.tlsKeyLog,
+		basicAuth:         k.basicAuth,
+		bearerFile:        k.bearerFile,
+		hmacSHA256:        k.hmacSHA256,
+		awsSigV4:          k.awsSigV4,
+		oauth2TokenURL:    k.oauth2TokenURL,
+		clientID:          k.clientID,
+		clientSecret:      k.clientSecret,
+		scope:             k.scope,
+		cookies:           k.cookies,
+		scenarioPath:      k.scenarioPath,
+		thinkTime:         thinkTime,
+		pacing:            k.pacing,
 		printLatencies:    k.latencies,
 		insecure:          k.insecure,
 		disableKeepAlives: k.disableKeepAlives,
@@ -267,6 +582,38 @@ func ParsePrintSpec(spec string) (bool, bool, bool, error) {
 	return pi, pp, pr, nil
 }
 
+var unixSchemes = map[string]string{
+	"http+unix://":  "http://",
+	"https+unix://": "https://",
+}
+
+// SplitUnixSocketURL extracts the socket path from http+unix:// and
+// https+unix:// URLs, where the host part is the percent-encoded path
+// to the socket, i.e. http+unix://%2Fvar%2Frun%2Fapp.sock/status.
+// The returned URL uses plain http(s) scheme and localhost as a host,
+// other URLs are returned unchanged along with an empty socket path.
+func SplitUnixSocketURL(raw string) (string, string, error) {
+	for prefix, scheme := range unixSchemes {
+		if !strings.HasPrefix(raw, prefix) {
+			continue
+		}
+		rest := raw[len(prefix):]
+		host, path := rest, ""
+		if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+			host, path = rest[:i], rest[i:]
+		}
+		socket, err := url.PathUnescape(host)
+		if err != nil || socket == "" {
+			return "", "", fmt.Errorf(
+				"%v does not appear to be a valid unix socket URL",
+				raw,
+			)
+		}
+		return scheme + "localhost" + path, socket, nil
+	}
+	return raw, "", nil
+}
+
 var re = regexp.MustCompile(`^(?P<proto>.+?:\/\/)?.*$`)
 
 func TryParseURL(raw string) (string, error) {
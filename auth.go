@@ -0,0 +1,347 @@
+package bombardier
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authorizer computes authorization headers for each request.
+type Authorizer interface {
+	// Authorize returns headers that should be added to the request.
+	// body is nil for streamed bodies.
+	Authorize(method, host string, u *url.URL, body *string) (
+		[]Header, error,
+	)
+}
+
+// NewAuthorizer returns Authorizer for authentication mode selected
+// in c or nil if none was selected. OAuth 2.0 token is obtained right
+// away using tlsConfig of the test, while errors of subsequent
+// refreshes are recorded in errors.
+func NewAuthorizer(
+	c Config, tlsConfig *tls.Config, errors *ErrorMap,
+) (Authorizer, error) {
+	switch AuthMode(c) {
+	case "basic":
+		return NewBasicAuthorizer(c.basicAuth), nil
+	case "bearer":
+		return NewBearerFileAuthorizer(c.bearerFile)
+	case "hmac-sha256":
+		return NewHMACAuthorizer(c.hmacSHA256)
+	case "aws-sigv4":
+		return NewAWSSigV4Authorizer(c.awsSigV4)
+	case "oauth2":
+		o := NewOAuth2Authorizer(c, tlsConfig, errors)
+		if err := o.Refresh(); err != nil {
+			return nil, err
+		}
+		return o, nil
+	}
+	return nil, nil
+}
+
+// AuthMode returns name of the authentication mode selected in c. Only
+// one of them may be selected, but should there be more, the first one
+// checked here is used.
+func AuthMode(c Config) string {
+	switch {
+	case c.basicAuth != "":
+		return "basic"
+	case c.bearerFile != "":
+		return "bearer"
+	case c.hmacSHA256 != "":
+		return "hmac-sha256"
+	case c.awsSigV4 != "":
+		return "aws-sigv4"
+	case c.oauth2TokenURL != "":
+		return "oauth2"
+	}
+	return ""
+}
+
+// BasicAuthorizer adds the same Basic Authorization header to every
+// request.
+type BasicAuthorizer struct {
+	headers []Header
+}
+
+func NewBasicAuthorizer(userPass string) *BasicAuthorizer {
+	creds := base64.StdEncoding.EncodeToString([]byte(userPass))
+	return &BasicAuthorizer{
+		headers: []Header{{"Authorization", "Basic " + creds}},
+	}
+}
+
+func (b *BasicAuthorizer) Authorize(
+	method, host string, u *url.URL, body *string,
+) ([]Header, error) {
+	return b.headers, nil
+}
+
+const bearerFileCheckInterval = time.Second
+
+// BearerFileAuthorizer adds Bearer token read from the file to every
+// request. The file is checked for modifications at most once per
+// second, so that tokens rotated by external tools are picked up.
+type BearerFileAuthorizer struct {
+	path string
+
+	mu        sync.RWMutex
+	headers   []Header
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+func NewBearerFileAuthorizer(path string) (*BearerFileAuthorizer, error) {
+	b := &BearerFileAuthorizer{path: path}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = b.load(fi.ModTime()); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *BearerFileAuthorizer) load(modTime time.Time) error {
+	token, err := ioutil.ReadFile(b.path)
+	if err != nil {
+		return err
+	}
+	trimmed := strings.TrimSpace(string(token))
+	if trimmed == "" {
+		return fmt.Errorf("no bearer token found in %v", b.path)
+	}
+	b.headers = []Header{{"Authorization", "Bearer " + trimmed}}
+	b.modTime = modTime
+	b.lastCheck = time.Now()
+	return nil
+}
+
+func (b *BearerFileAuthorizer) Authorize(
+	method, host string, u *url.URL, body *string,
+) ([]Header, error) {
+	b.mu.RLock()
+	headers, lastCheck := b.headers, b.lastCheck
+	b.mu.RUnlock()
+	if time.Since(lastCheck) < bearerFileCheckInterval {
+		return headers, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if time.Since(b.lastCheck) < bearerFileCheckInterval {
+		return b.headers, nil
+	}
+	b.lastCheck = time.Now()
+	fi, err := os.Stat(b.path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.ModTime().Equal(b.modTime) {
+		return b.headers, nil
+	}
+	if err = b.load(fi.ModTime()); err != nil {
+		return nil, err
+	}
+	return b.headers, nil
+}
+
+const hmacTimestampHeader = "X-Timestamp"
+
+// HMACAuthorizer signs every request with HMAC-SHA256. The signature
+// is computed over "METHOD\nREQUEST-URI\nTIMESTAMP\nBODY", hex-encoded
+// and sent in the user-selected header, while the timestamp (Unix time
+// in seconds) is sent in X-Timestamp header.
+type HMACAuthorizer struct {
+	key    []byte
+	header string
+
+	now func() time.Time
+}
+
+func NewHMACAuthorizer(keyHeader string) (*HMACAuthorizer, error) {
+	parts := strings.SplitN(keyHeader, ",", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, errInvalidHMACFormat
+	}
+	return &HMACAuthorizer{
+		key:    []byte(parts[0]),
+		header: strings.TrimSpace(parts[1]),
+		now:    time.Now,
+	}, nil
+}
+
+func (h *HMACAuthorizer) Authorize(
+	method, host string, u *url.URL, body *string,
+) ([]Header, error) {
+	if body == nil {
+		return nil, errSigningStreamedBody
+	}
+	timestamp := strconv.FormatInt(h.now().Unix(), decBase)
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write([]byte(method + "\n" + u.RequestURI() + "\n" +
+		timestamp + "\n"))
+	mac.Write([]byte(*body))
+	return []Header{
+		{h.header, hex.EncodeToString(mac.Sum(nil))},
+		{hmacTimestampHeader, timestamp},
+	}, nil
+}
+
+const (
+	awsSigV4Algorithm  = "AWS4-HMAC-SHA256"
+	awsSigV4DateFormat = "20060102T150405Z"
+)
+
+// AWSSigV4Authorizer signs every request with AWS Signature Version 4
+// using credentials from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// (optionally) AWS_SESSION_TOKEN environment variables.
+type AWSSigV4Authorizer struct {
+	region, service string
+
+	accessKeyID, secretAccessKey, sessionToken string
+
+	now func() time.Time
+}
+
+func NewAWSSigV4Authorizer(
+	regionService string,
+) (*AWSSigV4Authorizer, error) {
+	parts := strings.Split(regionService, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, errInvalidAWSSigV4Format
+	}
+	a := &AWSSigV4Authorizer{
+		region:          parts[0],
+		service:         parts[1],
+		accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		now:             time.Now,
+	}
+	if a.accessKeyID == "" || a.secretAccessKey == "" {
+		return nil, errNoAWSCredentials
+	}
+	return a, nil
+}
+
+func (a *AWSSigV4Authorizer) Authorize(
+	method, host string, u *url.URL, body *string,
+) ([]Header, error) {
+	if body == nil {
+		return nil, errSigningStreamedBody
+	}
+	now := a.now().UTC()
+	amzDate := now.Format(awsSigV4DateFormat)
+	payloadHash := sha256.Sum256([]byte(*body))
+	signed := []Header{
+		{"host", host},
+		{"x-amz-date", amzDate},
+	}
+	res := []Header{{"X-Amz-Date", amzDate}}
+	// Only S3 requires payload hash to be sent alongside with request
+	if a.service == "s3" {
+		hash := hex.EncodeToString(payloadHash[:])
+		signed = append(signed, Header{"x-amz-content-sha256", hash})
+		res = append(res, Header{"X-Amz-Content-Sha256", hash})
+	}
+	if a.sessionToken != "" {
+		signed = append(signed,
+			Header{"x-amz-security-token", a.sessionToken})
+		res = append(res,
+			Header{"X-Amz-Security-Token", a.sessionToken})
+	}
+	sort.Slice(signed, func(i, j int) bool {
+		return signed[i].key < signed[j].key
+	})
+
+	var canonicalHeaders, signedHeaders strings.Builder
+	for i, h := range signed {
+		if i != 0 {
+			signedHeaders.WriteByte(';')
+		}
+		signedHeaders.WriteString(h.key)
+		canonicalHeaders.WriteString(h.key + ":" + h.value + "\n")
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	// Every service, except for S3, expects path to be encoded twice
+	if a.service != "s3" {
+		path = awsEscape(path, false)
+	}
+	canonicalRequest := strings.Join([]string{
+		method,
+		path,
+		awsCanonicalQuery(u.Query()),
+		canonicalHeaders.String(),
+		signedHeaders.String(),
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	date := amzDate[:8]
+	scope := date + "/" + a.region + "/" + a.service + "/aws4_request"
+	crHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := awsSigV4Algorithm + "\n" + amzDate + "\n" + scope +
+		"\n" + hex.EncodeToString(crHash[:])
+	key := hmacSHA256([]byte("AWS4"+a.secretAccessKey), date)
+	key = hmacSHA256(key, a.region)
+	key = hmacSHA256(key, a.service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return append(res, Header{
+		"Authorization",
+		awsSigV4Algorithm + " Credential=" + a.accessKeyID + "/" + scope +
+			", SignedHeaders=" + signedHeaders.String() +
+			", Signature=" + signature,
+	}), nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func awsCanonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for k, vs := range query {
+		for _, v := range vs {
+			pairs = append(pairs, awsEscape(k, true)+"="+awsEscape(v, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// awsEscape percent-encodes everything except for unreserved
+// characters (and slashes, unless escapeSlash is set).
+func awsEscape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' ||
+			'0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' ||
+			c == '~' || (c == '/' && !escapeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,139 @@
+package bombardier
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Links are shaped in chunks small enough for the data to flow
+// smoothly rather than in bursts.
+const linkChunksPerSecond = 20
+
+var rateUnits = []struct {
+	suffix string
+	scale  float64
+}{
+	// Longer suffixes go first, since they end with the shorter ones
+	{"gbps", 1e9 / 8}, {"mbps", 1e6 / 8}, {"kbps", 1e3 / 8}, {"bps", 1.0 / 8},
+	{"gb", 1e9}, {"mb", 1e6}, {"kb", 1e3}, {"b", 1},
+}
+
+// BandwidthLimit limits rates(in bytes per second) data is sent(up) and
+// received(down) at over each of the connections. Rates are given
+// either in bytes(B, KB, MB, GB) or bits(bps, kbps, mbps, gbps) per
+// second and 0 leaves the direction unlimited.
+type BandwidthLimit struct {
+	value    string
+	up, down uint64
+}
+
+func (b *BandwidthLimit) String() string {
+	return b.value
+}
+
+func (b *BandwidthLimit) Set(value string) error {
+	parts := strings.Split(value, ",")
+	if len(parts) > 2 {
+		return errInvalidBandwidthLimit
+	}
+	rates := make([]uint64, 0, 2)
+	for _, p := range parts {
+		rate, err := parseRate(p)
+		if err != nil {
+			return err
+		}
+		rates = append(rates, rate)
+	}
+	if len(rates) == 1 {
+		rates = append(rates, rates[0])
+	}
+	*b = BandwidthLimit{value: value, up: rates[0], down: rates[1]}
+	return nil
+}
+
+// parseRate parses rate such as 256KB, 512KB/s or 1.5mbps to bytes per
+// second.
+func parseRate(s string) (uint64, error) {
+	s = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "/s")
+	scale := 1.0
+	for _, u := range rateUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, scale = strings.TrimSuffix(s, u.suffix), u.scale
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, errInvalidBandwidthLimit
+	}
+	rate := n * scale
+	if rate > 0 && rate < 1 {
+		return 1, nil
+	}
+	return uint64(rate), nil
+}
+
+// link delays data passing through one direction of a connection, so
+// that it doesn't flow faster than rate(in bytes per second, unlimited
+// if 0) and each read or write takes at least latency.
+type link struct {
+	rate    uint64
+	latency time.Duration
+
+	mu   sync.Mutex
+	free time.Time
+}
+
+// newLinks returns links shaping data sent and received over a single
+// connection.
+func newLinks(
+	limit *BandwidthLimit, latency time.Duration,
+) (up, down *link) {
+	up, down = &link{latency: latency}, &link{latency: latency}
+	if limit != nil {
+		up.rate, down.rate = limit.up, limit.down
+	}
+	return up, down
+}
+
+// delay waits for the latency of a single read or write.
+func (l *link) delay() {
+	if l.latency > 0 {
+		time.Sleep(l.latency)
+	}
+}
+
+// chunk returns how many out of n bytes may pass through at once.
+func (l *link) chunk(n int) int {
+	if l.rate == 0 {
+		return n
+	}
+	max := int(l.rate / linkChunksPerSecond)
+	if max < 1 {
+		max = 1
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// pass blocks until n bytes pass through the link.
+func (l *link) pass(n int) {
+	if l.rate == 0 || n <= 0 {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.free.Before(now) {
+		l.free = now
+	}
+	l.free = l.free.Add(
+		time.Duration(uint64(n) * uint64(time.Second) / l.rate),
+	)
+	wait := time.Until(l.free)
+	l.mu.Unlock()
+	time.Sleep(wait)
+}
@@ -0,0 +1,139 @@
+package bombardier
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestBandwidthLimitParsing(t *testing.T) {
+	expectations := []struct {
+		in       string
+		up, down uint64
+		err      error
+	}{
+		{"1000", 1000, 1000, nil},
+		{"256KB", 256000, 256000, nil},
+		{"256kb/s", 256000, 256000, nil},
+		{"1.5MB", 1500000, 1500000, nil},
+		{"2GB", 2000000000, 2000000000, nil},
+		{"64B", 64, 64, nil},
+		{"8mbps", 1000000, 1000000, nil},
+		{"1mbps,8mbps", 125000, 1000000, nil},
+		{"512kbps, 0", 64000, 0, nil},
+		{"4bps", 1, 1, nil},
+		{"1gbps", 125000000, 125000000, nil},
+		{"", 0, 0, errInvalidBandwidthLimit},
+		{"fast", 0, 0, errInvalidBandwidthLimit},
+		{"-1KB", 0, 0, errInvalidBandwidthLimit},
+		{"1KB,2KB,3KB", 0, 0, errInvalidBandwidthLimit},
+		{"1KB,", 0, 0, errInvalidBandwidthLimit},
+	}
+	for _, e := range expectations {
+		var b BandwidthLimit
+		err := b.Set(e.in)
+		if err != e.err {
+			t.Errorf("%q: expected %v, but got %v", e.in, e.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if b.up != e.up || b.down != e.down {
+			t.Errorf("%q: expected %v up and %v down, but got %v and %v",
+				e.in, e.up, e.down, b.up, b.down)
+		}
+		if b.String() != e.in {
+			t.Errorf("expected %q, but got %q", e.in, b.String())
+		}
+	}
+}
+
+func TestLinkChunk(t *testing.T) {
+	unlimited := &link{}
+	if c := unlimited.chunk(4096); c != 4096 {
+		t.Errorf("expected 4096, but got %v", c)
+	}
+	slow := &link{rate: 1000}
+	if c := slow.chunk(4096); c != 1000/linkChunksPerSecond {
+		t.Errorf("expected %v, but got %v", 1000/linkChunksPerSecond, c)
+	}
+	if c := slow.chunk(10); c != 10 {
+		t.Errorf("expected 10, but got %v", c)
+	}
+	crawling := &link{rate: 1}
+	if c := crawling.chunk(4096); c != 1 {
+		t.Errorf("expected 1, but got %v", c)
+	}
+}
+
+func TestLinkPass(t *testing.T) {
+	l := &link{rate: 10000}
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		l.pass(250)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected 1000 bytes to take 100ms, but it took %v",
+			elapsed)
+	}
+}
+
+func TestNewLinks(t *testing.T) {
+	up, down := newLinks(
+		&BandwidthLimit{up: 100, down: 200}, time.Millisecond,
+	)
+	if up.rate != 100 || down.rate != 200 {
+		t.Errorf("expected 100 up and 200 down, but got %v and %v",
+			up.rate, down.rate)
+	}
+	if up.latency != time.Millisecond || down.latency != time.Millisecond {
+		t.Errorf("expected 1ms latency, but got %v and %v",
+			up.latency, down.latency)
+	}
+	up, down = newLinks(nil, time.Millisecond)
+	if up.rate != 0 || down.rate != 0 {
+		t.Error("expected unlimited links")
+	}
+}
+
+func TestCountingConnShapesData(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	var read, written int64
+	conn := &CountingConn{
+		Conn:         client,
+		bytesRead:    &read,
+		bytesWritten: &written,
+	}
+	conn.up, conn.down = newLinks(
+		&BandwidthLimit{up: 10000, down: 10000}, 10*time.Millisecond,
+	)
+	defer conn.Close()
+	data := make([]byte, 1000)
+	go func() {
+		buf := make([]byte, len(data))
+		_, _ = io.ReadFull(server, buf)
+		_, _ = server.Write(buf)
+	}()
+	start := time.Now()
+	if n, err := conn.Write(data); err != nil || n != len(data) {
+		t.Fatalf("expected %v bytes to be written, but got %v(%v)",
+			len(data), n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected write to take 100ms, but it took %v", elapsed)
+	}
+	start = time.Now()
+	if _, err := io.ReadFull(conn, data); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected read to take 100ms, but it took %v", elapsed)
+	}
+	if read != int64(len(data)) || written != int64(len(data)) {
+		t.Errorf("expected %v bytes both ways, but got %v read and %v "+
+			"written", len(data), read, written)
+	}
+}
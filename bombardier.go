@@ -1,11 +1,15 @@
 package bombardier
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
+	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -31,6 +35,9 @@ type Bombardier struct {
 	req5xx uint64
 	others uint64
 
+	// Responses with each of the status codes
+	statusCodes *StatusCodes
+
 	conf        Config
 	barrier     CompletionBarrier
 	ratelimiter Limiter
@@ -40,9 +47,23 @@ type Bombardier struct {
 	latencies *uhist.Histogram
 	requests  *fhist.Histogram
 
+	// Latencies of successful(2xx and 3xx), 4xx and 5xx responses and
+	// of requests that failed without one
+	successLatencies *uhist.Histogram
+	latencies4xx     *uhist.Histogram
+	latencies5xx     *uhist.Histogram
+	errorLatencies   *uhist.Histogram
+
+	// Time taken to connect to the target through the proxy
+	proxyLatencies *uhist.Histogram
+
 	client   Client
 	doneChan chan struct{}
 
+	// Clients owning a single connection each, workers are pinned to
+	// them if there are any
+	connClients []Client
+
 	// RPS metrics
 	rpl   sync.Mutex
 	reqs  int64
@@ -51,6 +72,42 @@ type Bombardier struct {
 	// Errors
 	errors *ErrorMap
 
+	// Per-address statistics
+	addrs *AddrMap
+
+	// Negotiated TLS parameters
+	tlsStats *TLSStats
+
+	// Client certificates rotated across connections
+	identities *ClientIdentities
+
+	// Computes authorization headers for requests
+	auth Authorizer
+
+	// File TLS secrets are written to
+	keyLog io.Closer
+
+	// Steps performed by workers in each iteration, if any
+	scenario *Scenario
+
+	// Decodes compressed responses
+	decompressor *Decompressor
+
+	// Depth of pipelines and pipelining errors
+	pipeline *PipelineStats
+
+	// Connections opened, closed by server and reused
+	conns *ConnStats
+
+	// HTTP/2 streams in flight per connection
+	streams *StreamStats
+
+	// Responses and errors kept for debugging
+	sampler *ResponseSampler
+
+	// Requests written to a file for diagnostics
+	traceLog *TraceLog
+
 	// Progress bar
 	bar *pb.ProgressBar
 
@@ -66,6 +123,11 @@ func NewBombardier(c Config) (*Bombardier, error) {
 	b := new(Bombardier)
 	b.conf = c
 	b.latencies = uhist.Default()
+	b.successLatencies = uhist.Default()
+	b.latencies4xx = uhist.Default()
+	b.latencies5xx = uhist.Default()
+	b.errorLatencies = uhist.Default()
+	b.statusCodes = NewStatusCodes(c.statusLatencies)
 	b.requests = fhist.Default()
 
 	if b.conf.TestType() == counted {
@@ -92,16 +154,66 @@ func NewBombardier(c Config) (*Bombardier, error) {
 
 	b.out = os.Stdout
 
+	b.errors = NewErrorMap()
 	tlsConfig, err := GenerateTLSConfig(c)
 	if err != nil {
 		return nil, err
 	}
+	if keyLog, ok := tlsConfig.KeyLogWriter.(io.Closer); ok {
+		b.keyLog = keyLog
+	}
+	// Files opened while setting the test up are closed once it's over,
+	// so they are closed right away if the setup fails
+	setUp := false
+	defer func() {
+		if setUp {
+			return
+		}
+		if b.keyLog != nil {
+			_ = b.keyLog.Close()
+		}
+		if b.traceLog != nil {
+			_ = b.traceLog.Close()
+		}
+	}()
+	auth, err := NewAuthorizer(c, tlsConfig, b.errors)
+	if err != nil {
+		return nil, err
+	}
+	b.auth = auth
+
+	if c.scenarioPath != "" {
+		b.scenario, err = LoadScenario(c.scenarioPath, c.url)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(tlsConfig.Certificates) > 1 {
+		// Paths were already validated by GenerateTLSConfig
+		certPaths, _, _ := ClientCertPaths(c.certPath, c.keyPath)
+		b.identities = NewClientIdentities(
+			certPaths, tlsConfig.Certificates,
+		)
+	}
 
 	var (
-		pbody *string
-		bsp   BodyStreamProducer
+		pbody       *string
+		bsp         BodyStreamProducer
+		contentType string
 	)
-	if c.stream {
+	if c.form != nil {
+		if c.stream {
+			bsp, contentType, err = c.form.BodyStreamProducer()
+		} else {
+			var body string
+			body, contentType, err = c.form.Body()
+			pbody = &body
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else if c.stream {
 		if c.bodyFilePath != "" {
 			bsp = func() (io.ReadCloser, error) {
 				return os.Open(c.bodyFilePath)
@@ -126,6 +238,44 @@ func NewBombardier(c Config) (*Bombardier, error) {
 		}
 	}
 
+	if c.connectTo != nil || c.resolve != nil || c.spreadAddrs {
+		b.addrs = NewAddrMap()
+	}
+	if c.proxy != "" {
+		b.proxyLatencies = uhist.Default()
+	}
+	b.tlsStats = NewTLSStats()
+	b.conns = NewConnStats()
+
+	if c.compressBody != "" {
+		pbody, bsp, err = compressedBody(c.compressBody, pbody, bsp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.decompress {
+		b.decompressor = NewDecompressor()
+	}
+	if c.pipeline > 0 {
+		b.pipeline = NewPipelineStats()
+	}
+	if c.sampleResponses > 0 {
+		b.sampler = NewResponseSampler(c.sampleResponses)
+	}
+
+	headers := c.headers
+	for _, h := range []Header{
+		{"Content-Type", contentType},
+		{"Content-Encoding", c.compressBody},
+		{"Accept-Encoding", c.acceptEncoding},
+	} {
+		if h.value != "" && !headers.Has(h.key) {
+			withDefault := append(HeadersList{}, *headers...)
+			withDefault = append(withDefault, h)
+			headers = &withDefault
+		}
+	}
+
 	cc := &ClientOpts{
 		HTTP2:             false,
 		maxConns:          c.numConns,
@@ -133,15 +283,50 @@ func NewBombardier(c Config) (*Bombardier, error) {
 		tlsConfig:         tlsConfig,
 		disableKeepAlives: c.disableKeepAlives,
 
-		headers:      c.headers,
-		url:          c.url,
-		method:       c.method,
-		body:         pbody,
-		bodProd:      bsp,
-		bytesRead:    &b.bytesRead,
-		bytesWritten: &b.bytesWritten,
+		headers:        headers,
+		url:            c.url,
+		method:         c.method,
+		unixSocket:     c.unixSocket,
+		connectTo:      c.connectTo,
+		resolve:        c.resolve,
+		spreadAddrs:    c.spreadAddrs,
+		localAddrs:     c.localAddrs,
+		ipVersion:      c.ipVersion,
+		proxy:          c.proxy,
+		body:           pbody,
+		bodProd:        bsp,
+		bytesRead:      &b.bytesRead,
+		bytesWritten:   &b.bytesWritten,
+		addrs:          b.addrs,
+		proxyLatencies: b.proxyLatencies,
+		tlsStats:       b.tlsStats,
+		identities:     b.identities,
+		auth:           b.auth,
+		decompressor:   b.decompressor,
+		pipeline:       c.pipeline,
+		pipelineStats:  b.pipeline,
+		connStats:      b.conns,
+		bandwidth:      c.bandwidth,
+		connLatency:    c.connLatency,
+		sampler:        b.sampler,
+	}
+	if c.cookies == cookiesShared {
+		cc.cookieJar = NewCookieJar()
 	}
 	b.client = MakeHTTPClient(c.clientType, cc)
+	if c.traceLog != "" {
+		b.traceLog, err = NewTraceLog(c.traceLog)
+		if err != nil {
+			return nil, err
+		}
+		b.client = WithTrace(b.client, b.traceLog.Worker(0))
+	}
+	if c.streamsPerConn > 0 {
+		b.streams = NewStreamStats()
+	}
+	if c.PinsConnections() {
+		b.connClients = b.makeConnClients(cc)
+	}
 
 	if !b.conf.printProgress {
 		b.bar.Output = ioutil.Discard
@@ -153,12 +338,66 @@ func NewBombardier(c Config) (*Bombardier, error) {
 		return nil, err
 	}
 
-	b.wg.Add(int(c.numConns))
-	b.errors = NewErrorMap()
+	b.wg.Add(int(c.Workers()))
 	b.doneChan = make(chan struct{}, 2)
+	setUp = true
 	return b, nil
 }
 
+// compressedBody compresses either the body or the stream produced by
+// bsp once and returns body or producer serving compressed data.
+func compressedBody(
+	encoding string, body *string, bsp BodyStreamProducer,
+) (*string, BodyStreamProducer, error) {
+	var raw string
+	if body != nil {
+		raw = *body
+	} else {
+		rc, err := bsp()
+		if err != nil {
+			return nil, nil, err
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		raw = string(data)
+	}
+	compressed, err := CompressBody(encoding, raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if body != nil {
+		return &compressed, nil, nil
+	}
+	return nil, func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(
+			ProxyReader{strings.NewReader(compressed)},
+		), nil
+	}, nil
+}
+
+// makeConnClients makes a client owning a single connection for each
+// of the connections.
+func (b *Bombardier) makeConnClients(opts *ClientOpts) []Client {
+	clients := make([]Client, b.conf.numConns)
+	for i := range clients {
+		cc := *opts
+		cc.maxConns = 1
+		if b.conf.LimitsConnLifetime() {
+			cc.lifetime = NewConnLifetime(
+				b.conf.maxConnRequests, b.conf.maxConnAge,
+			)
+		}
+		if b.streams != nil {
+			cc.streams = b.streams.Conn()
+		}
+		clients[i] = MakeHTTPClient(b.conf.clientType, &cc)
+	}
+	return clients
+}
+
 func MakeHTTPClient(clientType ClientTyp, cc *ClientOpts) Client {
 	var cl Client
 	switch clientType {
@@ -210,6 +449,10 @@ func (b *Bombardier) PrepareTemplate() (*template.Template, error) {
 			"StringToBytes": func(s string) []byte {
 				return []byte(s)
 			},
+			"JSONString": func(s string) (string, error) {
+				data, err := json.Marshal(s)
+				return string(data), err
+			},
 			"UUIDV1": uuid.NewV1,
 			"UUIDV2": uuid.NewV2,
 			"UUIDV3": uuid.NewV3,
@@ -230,40 +473,110 @@ func (b *Bombardier) WriteStatistics(
 	b.rpl.Lock()
 	b.reqs++
 	b.rpl.Unlock()
-	var counter *uint64
+	var (
+		counter   *uint64
+		latencies *uhist.Histogram
+	)
 	switch code / 100 {
 	case 1:
 		counter = &b.req1xx
 	case 2:
-		counter = &b.req2xx
+		counter, latencies = &b.req2xx, b.successLatencies
 	case 3:
-		counter = &b.req3xx
+		counter, latencies = &b.req3xx, b.successLatencies
 	case 4:
-		counter = &b.req4xx
+		counter, latencies = &b.req4xx, b.latencies4xx
 	case 5:
-		counter = &b.req5xx
+		counter, latencies = &b.req5xx, b.latencies5xx
 	default:
 		counter = &b.others
+		if code < minStatusCode {
+			// No response was received
+			latencies = b.errorLatencies
+		}
 	}
 	atomic.AddUint64(counter, 1)
+	if latencies != nil {
+		latencies.Increment(usTaken)
+	}
+	b.statusCodes.Add(code, usTaken)
 }
 
 func (b *Bombardier) PerformSingleRequest() {
-	code, usTaken, err := b.client.Do()
+	b.performRequest(b.client)
+}
+
+func (b *Bombardier) performRequest(client Client) {
+	code, usTaken, err := client.Do()
 	if err != nil {
-		b.errors.Add(err)
+		b.addError(err)
 	}
 	b.WriteStatistics(code, usTaken)
 }
 
-func (b *Bombardier) Worker() {
+func (b *Bombardier) performIteration(
+	client ScenarioClient, pause func() bool,
+) {
+	b.scenario.Run(client, func(code int, usTaken uint64, err error) {
+		if err != nil {
+			b.addError(err)
+		}
+		b.WriteStatistics(code, usTaken)
+	}, pause)
+}
+
+func (b *Bombardier) addError(err error) {
+	b.errors.Add(err)
+	if b.sampler != nil {
+		b.sampler.Error(err)
+	}
+}
+
+// workerClient returns client the i-th worker sends requests with.
+func (b *Bombardier) workerClient(i uint64) Client {
+	client := b.client
+	if len(b.connClients) > 0 {
+		client = b.connClients[i%uint64(len(b.connClients))]
+	}
+	if b.traceLog != nil {
+		client = WithTrace(client, b.traceLog.Worker(i))
+	}
+	return client
+}
+
+func (b *Bombardier) Worker(client Client) {
 	done := b.barrier.Done()
+	if b.conf.cookies == cookiesPerWorker {
+		client = WithCookieJar(client, NewCookieJar())
+	}
+	think := func() bool {
+		return true
+	}
+	if b.conf.thinkTime != nil {
+		rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+		think = func() bool {
+			return sleep(b.conf.thinkTime.Next(rnd), done)
+		}
+	}
 	for b.barrier.TryGrabWork() {
 		if b.ratelimiter.Pace(done) == brk {
 			break
 		}
-		b.PerformSingleRequest()
+		start := time.Now()
+		if b.scenario != nil {
+			// Both of the clients are able to perform scenarios
+			b.performIteration(client.(ScenarioClient), think)
+		} else {
+			b.performRequest(client)
+		}
 		b.barrier.JobDone()
+		if !think() {
+			break
+		}
+		if b.conf.pacing > 0 &&
+			!sleep(b.conf.pacing-time.Since(start), done) {
+			break
+		}
 	}
 }
 
@@ -331,10 +644,21 @@ func (b *Bombardier) Bombard() {
 	b.bar.Start()
 	bombardmentBegin := time.Now()
 	b.start = time.Now()
-	for i := uint64(0); i < b.conf.numConns; i++ {
+	b.errors.Start(bombardmentBegin, b.conf.errorHistogram)
+	refresherDone := make(chan struct{})
+	if oauth2, ok := b.auth.(*OAuth2Authorizer); ok {
+		go func() {
+			defer close(refresherDone)
+			oauth2.RefreshInBackground(b.barrier.Done())
+		}()
+	} else {
+		close(refresherDone)
+	}
+	for i := uint64(0); i < b.conf.Workers(); i++ {
+		client := b.workerClient(i)
 		go func() {
 			defer b.wg.Done()
-			b.Worker()
+			b.Worker(client)
 		}()
 	}
 	go b.RateMeter()
@@ -343,16 +667,52 @@ func (b *Bombardier) Bombard() {
 	b.timeTaken = time.Since(bombardmentBegin)
 	<-b.doneChan
 	<-b.doneChan
+	<-refresherDone
+	// Connections may still be written to while they are closed, so
+	// they are closed before the statistics are gathered
+	CloseIdleConnections(b.client)
+	for _, c := range b.connClients {
+		CloseIdleConnections(c)
+	}
+	if b.keyLog != nil {
+		if err := b.keyLog.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if b.sampler != nil && b.conf.sampleDir != "" {
+		if err := b.sampler.WriteTo(b.conf.sampleDir); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if b.traceLog != nil {
+		if err := b.traceLog.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
 }
 
 func (b *Bombardier) PrintIntro() {
+	target := b.conf.url
+	if b.conf.unixSocket != "" {
+		target += " (unix:" + b.conf.unixSocket + ")"
+	}
+	if b.conf.proxy != "" {
+		target += " (via " + RedactedProxyURL(b.conf.proxy) + ")"
+	}
+	if b.scenario != nil {
+		target += " (scenario " + b.conf.scenarioPath + ")"
+	}
+	conns := fmt.Sprintf("%v connection(s)", b.conf.numConns)
+	if b.conf.streamsPerConn > 0 {
+		conns += fmt.Sprintf(" with %v stream(s) each", b.conf.streamsPerConn)
+	}
 	if b.conf.TestType() == counted {
 		fmt.Fprintf(b.out,
-			"Bombarding %v with %v request(s) using %v connection(s)\n",
-			b.conf.url, *b.conf.numReqs, b.conf.numConns)
+			"Bombarding %v with %v request(s) using %v\n",
+			target, *b.conf.numReqs, conns)
 	} else if b.conf.TestType() == timed {
-		fmt.Fprintf(b.out, "Bombarding %v for %v using %v connection(s)\n",
-			b.conf.url, *b.conf.duration, b.conf.numConns)
+		fmt.Fprintf(b.out, "Bombarding %v for %v using %v\n",
+			target, *b.conf.duration, conns)
 	}
 }
 
@@ -361,8 +721,12 @@ func (b *Bombardier) GatherInfo() internal.TestInfo {
 		Spec: internal.Spec{
 			NumberOfConnections: b.conf.numConns,
 
-			Method: b.conf.method,
-			URL:    b.conf.url,
+			Method:     b.conf.method,
+			URL:        b.conf.url,
+			UnixSocket: b.conf.unixSocket,
+
+			SpreadAddrs: b.conf.spreadAddrs,
+			IPVersion:   b.conf.ipVersion,
 
 			Body:         b.conf.body,
 			BodyFilePath: b.conf.bodyFilePath,
@@ -370,6 +734,47 @@ func (b *Bombardier) GatherInfo() internal.TestInfo {
 			CertPath: b.conf.certPath,
 			KeyPath:  b.conf.keyPath,
 
+			CACertPath:    b.conf.caCertPath,
+			SNI:           b.conf.sni,
+			TLSMinVersion: b.conf.tlsMinVersion,
+			TLSMaxVersion: b.conf.tlsMaxVersion,
+			Ciphers:       b.conf.ciphers,
+			Curves:        b.conf.curves,
+			ALPN:          b.conf.alpn,
+			TLSResumption: b.conf.tlsResumption,
+			TLSKeyLog:     b.conf.tlsKeyLog,
+
+			Auth:           AuthMode(b.conf),
+			OAuth2TokenURL: b.conf.oauth2TokenURL,
+			OAuth2Scope:    b.conf.scope,
+
+			Cookies: b.conf.cookies,
+
+			Pacing: b.conf.pacing,
+
+			Pipeline: b.conf.pipeline,
+
+			MaxConnRequests: b.conf.maxConnRequests,
+			MaxConnAge:      b.conf.maxConnAge,
+
+			StreamsPerConnection: b.conf.streamsPerConn,
+
+			ConnLatency: b.conf.connLatency,
+
+			SampleResponses: b.conf.sampleResponses,
+			SampleDir:       b.conf.sampleDir,
+
+			TraceLog:       b.conf.traceLog,
+			ErrorHistogram: b.conf.errorHistogram,
+
+			StatusLatencies: b.conf.statusLatencies,
+
+			CompressBody:   b.conf.compressBody,
+			AcceptEncoding: b.conf.acceptEncoding,
+			Decompress:     b.conf.decompress,
+
+			Scenario: b.conf.scenarioPath,
+
 			Stream:     b.conf.stream,
 			Timeout:    b.conf.timeout,
 			ClientType: internal.ClientType(b.conf.clientType),
@@ -377,8 +782,8 @@ func (b *Bombardier) GatherInfo() internal.TestInfo {
 			Rate: b.conf.rate,
 		},
 		Result: internal.Results{
-			BytesRead:    b.bytesRead,
-			BytesWritten: b.bytesWritten,
+			BytesRead:    atomic.LoadInt64(&b.bytesRead),
+			BytesWritten: atomic.LoadInt64(&b.bytesWritten),
 			TimeTaken:    b.timeTaken,
 
 			Req1XX: b.req1xx,
@@ -390,6 +795,11 @@ func (b *Bombardier) GatherInfo() internal.TestInfo {
 
 			Latencies: b.latencies,
 			Requests:  b.requests,
+
+			SuccessLatencies: b.successLatencies,
+			Latencies4XX:     b.latencies4xx,
+			Latencies5XX:     b.latencies5xx,
+			ErrorLatencies:   b.errorLatencies,
 		},
 	}
 
@@ -411,17 +821,188 @@ func (b *Bombardier) GatherInfo() internal.TestInfo {
 		}
 	}
 
+	if b.conf.connectTo != nil {
+		info.Spec.ConnectTo = b.conf.connectTo.Entries()
+	}
+	if b.conf.resolve != nil {
+		info.Spec.Resolve = b.conf.resolve.Entries()
+	}
+	if b.conf.localAddrs != nil {
+		info.Spec.LocalAddrs = *b.conf.localAddrs
+	}
+	if b.conf.form != nil {
+		info.Spec.Form = b.conf.form.Entries()
+	}
+	if b.conf.bandwidth != nil {
+		info.Spec.BandwidthLimit = b.conf.bandwidth.String()
+	}
+	if b.conf.thinkTime != nil {
+		info.Spec.ThinkTime = b.conf.thinkTime.String()
+	}
+	if b.conf.proxy != "" {
+		info.Spec.Proxy = RedactedProxyURL(b.conf.proxy)
+		info.Result.ProxyLatencies = b.proxyLatencies
+	}
+
+	if b.addrs != nil {
+		for _, aws := range b.addrs.ByAddr() {
+			info.Result.Addresses = append(info.Result.Addresses,
+				internal.AddressStats{
+					Address:   aws.addr,
+					Count:     aws.count,
+					Latencies: aws.latencies,
+				})
+		}
+	}
+
+	for _, tpc := range b.tlsStats.ByFrequency() {
+		info.Result.TLS = append(info.Result.TLS,
+			internal.TLSParamsWithCount{
+				Version:     tpc.version,
+				CipherSuite: tpc.cipherSuite,
+				Count:       tpc.count,
+			})
+	}
+	full := b.tlsStats.fullHandshakes.Count()
+	resumed := b.tlsStats.resumedHandshakes.Count()
+	if full+resumed > 0 {
+		info.Result.TLSHandshakes = &internal.TLSHandshakes{
+			Full:             full,
+			Resumed:          resumed,
+			FullLatencies:    b.tlsStats.fullHandshakes,
+			ResumedLatencies: b.tlsStats.resumedHandshakes,
+		}
+	}
+
+	if b.identities != nil {
+		for _, iwc := range b.identities.ByIdentity() {
+			info.Result.ClientIdentities = append(
+				info.Result.ClientIdentities,
+				internal.IdentityWithCount{
+					Identity: iwc.identity,
+					Count:    iwc.count,
+				})
+		}
+	}
+
+	if b.scenario != nil {
+		ss := &internal.ScenarioStats{
+			Completed: atomic.LoadUint64(&b.scenario.completed),
+			Aborted:   atomic.LoadUint64(&b.scenario.aborted),
+			Latencies: b.scenario.latencies,
+		}
+		for _, step := range b.scenario.steps {
+			ss.Steps = append(ss.Steps, internal.StepStats{
+				Name:      step.name,
+				Count:     atomic.LoadUint64(&step.count),
+				Errors:    atomic.LoadUint64(&step.errors),
+				Latencies: step.latencies,
+			})
+		}
+		info.Result.Scenario = ss
+	}
+
+	if b.pipeline != nil {
+		avg, max := b.pipeline.Depth()
+		info.Result.Pipeline = &internal.Pipeline{
+			AvgDepth:  avg,
+			MaxDepth:  max,
+			Overflows: b.pipeline.Overflows(),
+			Stopped:   b.pipeline.Stopped(),
+		}
+	}
+	if b.streams != nil {
+		avg, max := b.streams.InFlight()
+		info.Result.Streams = &internal.Streams{
+			AvgInFlight: avg,
+			MaxInFlight: max,
+		}
+	}
+	if opened := b.conns.Opened(); opened > 0 {
+		info.Result.Connections = &internal.Connections{
+			Opened:         opened,
+			ClosedByServer: b.conns.ClosedByServer(),
+			Reused:         b.conns.Reused(),
+		}
+	}
+	if b.decompressor != nil {
+		info.Result.Decompression = &internal.Decompression{
+			Compressed:   b.decompressor.Compressed(),
+			Decompressed: b.decompressor.Decompressed(),
+			Errors:       b.decompressor.Errors(),
+		}
+	}
+
+	if b.sampler != nil {
+		info.Result.SamplesDir = b.conf.sampleDir
+		for _, g := range b.sampler.Groups() {
+			sg := internal.SampleGroup{
+				Status: g.status,
+				Error:  g.err,
+				Seen:   g.seen,
+			}
+			for _, rs := range g.Samples() {
+				sg.Samples = append(sg.Samples, internal.ResponseSample{
+					Status:    rs.status,
+					Error:     rs.err,
+					Headers:   sampleHeaders(rs.header),
+					Body:      string(rs.body),
+					Truncated: rs.truncated,
+				})
+			}
+			info.Result.Samples = append(info.Result.Samples, sg)
+		}
+	}
+
+	for _, code := range b.statusCodes.Seen() {
+		scc := internal.StatusCodeCount{
+			Code:  code,
+			Count: b.statusCodes.Count(code),
+		}
+		if latencies := b.statusCodes.Latencies(code); latencies != nil {
+			scc.Latencies = latencies
+		}
+		info.Result.StatusCodes = append(info.Result.StatusCodes, scc)
+	}
+
+	if b.traceLog != nil {
+		info.Result.TraceLog = &internal.TraceLog{
+			Written: b.traceLog.Written(),
+			Dropped: b.traceLog.Dropped(),
+		}
+	}
+
 	for _, ewc := range b.errors.ByFrequency() {
 		info.Result.Errors = append(info.Result.Errors,
 			internal.ErrorWithCount{
-				Error: ewc.error,
-				Count: ewc.count,
+				Class:     ewc.class,
+				Error:     ewc.error,
+				Count:     ewc.count,
+				First:     ewc.first,
+				Last:      ewc.last,
+				PerSecond: ewc.perSecond,
 			})
 	}
 
 	return info
 }
 
+// sampleHeaders returns headers of sampled response sorted by name.
+func sampleHeaders(header http.Header) []internal.Header {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var headers []internal.Header
+	for _, key := range keys {
+		for _, value := range header[key] {
+			headers = append(headers, internal.Header{Key: key, Value: value})
+		}
+	}
+	return headers
+}
+
 func (b *Bombardier) PrintStats() {
 	info := b.GatherInfo()
 	err := b.template.Execute(b.out, info)
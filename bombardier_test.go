@@ -2,21 +2,32 @@ package bombardier
 
 import (
 	"bytes"
+	"compress/gzip"
 	"container/ring"
+	"crypto/hmac"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+	"github.com/gho1b/bombardier/internal"
 	"github.com/valyala/fasthttp"
 )
 
@@ -740,3 +751,1623 @@ func testBombardierShouldSendCustomHostHeader(
 	b.DisableOutput()
 	b.Bombard()
 }
+
+func TestBombardierUnixSocket(t *testing.T) {
+	testAllClients(t, testBombardierUnixSocket)
+}
+
+func testBombardierUnixSocket(clientType ClientTyp, t *testing.T) {
+	dir, err := ioutil.TempDir("", "bombardier")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "test.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	reqsReceived := uint64(0)
+	s := &http.Server{
+		Handler: http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			atomic.AddUint64(&reqsReceived, 1)
+			if r.Host != "custom-host" {
+				t.Errorf("Host must be %q, but it's %q", "custom-host", r.Host)
+			}
+			if r.RequestURI != "/status" {
+				t.Errorf("Unexpected request URI: %q", r.RequestURI)
+			}
+		}),
+	}
+	go func() {
+		_ = s.Serve(ln)
+	}()
+	defer s.Close()
+	numReqs := uint64(10)
+	headers := HeadersList([]Header{
+		{"Host", "custom-host"},
+	})
+	b, e := NewBombardier(Config{
+		numConns:   defaultNumberOfConns,
+		numReqs:    &numReqs,
+		url:        "http://localhost:80/status",
+		unixSocket: socket,
+		headers:    &headers,
+		timeout:    defaultTimeout,
+		method:     "GET",
+		body:       "",
+		clientType: clientType,
+		format:     KnownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.DisableOutput()
+	b.Bombard()
+	if reqsReceived != numReqs {
+		t.Errorf("expected %v requests, but got %v", numReqs, reqsReceived)
+	}
+	if b.bytesRead == 0 || b.bytesWritten == 0 {
+		t.Errorf("bytes weren't counted: %v read, %v written",
+			b.bytesRead, b.bytesWritten)
+	}
+}
+
+func TestBombardierSpreadsConnections(t *testing.T) {
+	testAllClients(t, testBombardierSpreadsConnections)
+}
+
+func testBombardierSpreadsConnections(clientType ClientTyp, t *testing.T) {
+	ln, err := net.Listen("tcp", "0.0.0.0:0")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	s := httptest.NewUnstartedServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	s.Listener = ln
+	s.Start()
+	defer s.Close()
+	_, port, err := net.SplitHostPort(ln.Addr().String())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	resolve := new(ResolveList)
+	if err = resolve.Set("bombardier.test:" + port +
+		":127.0.0.1,127.0.0.2"); err != nil {
+		t.Error(err)
+		return
+	}
+	numReqs := uint64(100)
+	b, e := NewBombardier(Config{
+		numConns:    10,
+		numReqs:     &numReqs,
+		url:         "http://bombardier.test:" + port,
+		resolve:     resolve,
+		spreadAddrs: true,
+		headers:     new(HeadersList),
+		timeout:     defaultTimeout,
+		method:      "GET",
+		body:        "",
+		clientType:  clientType,
+		format:      KnownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.DisableOutput()
+	b.Bombard()
+	byAddr := b.addrs.ByAddr()
+	if len(byAddr) != 2 {
+		t.Errorf("expected requests to 2 addresses, but got %v", byAddr)
+		return
+	}
+	total := uint64(0)
+	for _, aws := range byAddr {
+		total += aws.count
+	}
+	if total != numReqs {
+		t.Errorf("expected %v requests in total, but got %v", numReqs, total)
+	}
+	info := b.GatherInfo()
+	if len(info.Result.Addresses) != 2 {
+		t.Errorf("expected 2 addresses in results, but got %v",
+			len(info.Result.Addresses))
+	}
+}
+
+func TestBombardierTLSParameters(t *testing.T) {
+	testAllClients(t, testBombardierTLSParameters)
+}
+
+func testBombardierTLSParameters(clientType ClientTyp, t *testing.T) {
+	s := httptest.NewUnstartedServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.TLS.ServerName != "example.com" {
+				t.Errorf("unexpected server name: %q", r.TLS.ServerName)
+			}
+		}),
+	)
+	s.TLS = &tls.Config{NextProtos: []string{"h2", "http/1.1"}}
+	s.StartTLS()
+	defer s.Close()
+	caCert, err := ioutil.TempFile("", "bombardier")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.Remove(caCert.Name())
+	err = pem.Encode(caCert, &pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: s.Certificate().Raw,
+	})
+	if cerr := caCert.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	numReqs := uint64(10)
+	b, e := NewBombardier(Config{
+		numConns:      defaultNumberOfConns,
+		numReqs:       &numReqs,
+		url:           s.URL,
+		headers:       new(HeadersList),
+		timeout:       defaultTimeout,
+		method:        "GET",
+		caCertPath:    caCert.Name(),
+		sni:           "example.com",
+		tlsMaxVersion: "1.2",
+		clientType:    clientType,
+		format:        KnownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.DisableOutput()
+	b.Bombard()
+	if b.req2xx != numReqs {
+		t.Errorf("expected %v successful requests, but got %v (%v)",
+			numReqs, b.req2xx, b.errors.ByFrequency())
+	}
+	info := b.GatherInfo()
+	if len(info.Result.TLS) == 0 {
+		t.Error("negotiated TLS parameters weren't recorded")
+	}
+	for _, p := range info.Result.TLS {
+		if p.Version != "TLS 1.2" {
+			t.Errorf("unexpected TLS version: %v", p.Version)
+		}
+	}
+}
+
+func TestBombardierTLSResumption(t *testing.T) {
+	testAllClients(t, testBombardierTLSResumption)
+}
+
+func testBombardierTLSResumption(clientType ClientTyp, t *testing.T) {
+	s := httptest.NewTLSServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	for _, mode := range []string{
+		tlsResumptionOff, tlsResumptionTickets, tlsResumptionCache,
+	} {
+		// fasthttp doesn't respect disableKeepAlives
+		headers := &HeadersList{{"Connection", "close"}}
+		numReqs := uint64(20)
+		b, e := NewBombardier(Config{
+			numConns:          2,
+			numReqs:           &numReqs,
+			url:               s.URL,
+			headers:           headers,
+			timeout:           defaultTimeout,
+			method:            "GET",
+			insecure:          true,
+			disableKeepAlives: true,
+			tlsResumption:     mode,
+			clientType:        clientType,
+			format:            KnownFormat("plain-text"),
+		})
+		if e != nil {
+			t.Error(e)
+			return
+		}
+		b.DisableOutput()
+		b.Bombard()
+		hs := b.GatherInfo().Result.TLSHandshakes
+		if hs == nil {
+			t.Errorf("%v: handshakes weren't recorded", mode)
+			continue
+		}
+		if hs.Full == 0 {
+			t.Errorf("%v: expected some full handshakes", mode)
+		}
+		if mode == tlsResumptionOff && hs.Resumed != 0 {
+			t.Errorf("%v: expected no resumed handshakes, but got %v",
+				mode, hs.Resumed)
+		}
+		if mode != tlsResumptionOff && hs.Resumed == 0 {
+			t.Errorf("%v: expected some resumed handshakes", mode)
+		}
+	}
+}
+
+func TestBombardierTLSKeyLog(t *testing.T) {
+	testAllClients(t, testBombardierTLSKeyLog)
+}
+
+func testBombardierTLSKeyLog(clientType ClientTyp, t *testing.T) {
+	s := httptest.NewTLSServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	keyLog, err := ioutil.TempFile("", "bombardier")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	keyLog.Close()
+	defer os.Remove(keyLog.Name())
+	numReqs := uint64(10)
+	b, e := NewBombardier(Config{
+		numConns:   defaultNumberOfConns,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(HeadersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		insecure:   true,
+		tlsKeyLog:  keyLog.Name(),
+		clientType: clientType,
+		format:     KnownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.DisableOutput()
+	b.Bombard()
+	keys, err := ioutil.ReadFile(keyLog.Name())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !bytes.Contains(keys, []byte("CLIENT_")) {
+		t.Errorf("expected TLS secrets to be logged, but got %q", keys)
+	}
+}
+
+func TestBombardierClosesTLSKeyLogOnSetupError(t *testing.T) {
+	if _, err := os.Stat("/proc/self/fd"); err != nil {
+		t.Skip("open files can't be listed:", err)
+	}
+	keyLog, err := ioutil.TempFile("", "bombardier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyLog.Close()
+	defer os.Remove(keyLog.Name())
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenURL := "http://" + l.Addr().String() + "/token"
+	_ = l.Close()
+	numReqs := uint64(1)
+	// OAuth2 token is obtained after the key log is opened
+	_, err = NewBombardier(Config{
+		numConns:       defaultNumberOfConns,
+		numReqs:        &numReqs,
+		url:            "https://localhost",
+		headers:        new(HeadersList),
+		timeout:        defaultTimeout,
+		method:         "GET",
+		tlsKeyLog:      keyLog.Name(),
+		oauth2TokenURL: tokenURL,
+		clientID:       "id",
+		clientType:     fhttp,
+		format:         KnownFormat("plain-text"),
+	})
+	if err == nil {
+		t.Fatal("expected token request to fail")
+	}
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fd := range fds {
+		path, _ := os.Readlink(filepath.Join("/proc/self/fd", fd.Name()))
+		if path == keyLog.Name() {
+			t.Fatalf("key log is still open as fd %v", fd.Name())
+		}
+	}
+}
+
+func TestBombardierRotatesClientCerts(t *testing.T) {
+	testAllClients(t, testBombardierRotatesClientCerts)
+}
+
+func testBombardierRotatesClientCerts(clientType ClientTyp, t *testing.T) {
+	dir, err := ioutil.TempDir("", "bombardier")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	names := []string{"alice", "bob", "carol"}
+	for _, name := range names {
+		writeTestClientCert(t, dir, name, ".crt")
+	}
+	var (
+		mu       sync.Mutex
+		received = make(map[string]uint64)
+	)
+	s := httptest.NewUnstartedServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			received[r.TLS.PeerCertificates[0].Subject.CommonName]++
+			mu.Unlock()
+		}),
+	)
+	s.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	s.StartTLS()
+	defer s.Close()
+	// fasthttp doesn't respect disableKeepAlives
+	headers := &HeadersList{{"Connection", "close"}}
+	numReqs := uint64(30)
+	b, e := NewBombardier(Config{
+		numConns:          3,
+		numReqs:           &numReqs,
+		url:               s.URL,
+		headers:           headers,
+		timeout:           defaultTimeout,
+		method:            "GET",
+		insecure:          true,
+		disableKeepAlives: true,
+		certPath:          dir,
+		clientType:        clientType,
+		format:            KnownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.DisableOutput()
+	b.Bombard()
+	if b.req2xx != numReqs {
+		t.Errorf("expected %v successful requests, but got %v (%v)",
+			numReqs, b.req2xx, b.errors.ByFrequency())
+	}
+	identities := b.GatherInfo().Result.ClientIdentities
+	if len(identities) != len(names) {
+		t.Fatalf("expected %v identities, but got %v",
+			len(names), identities)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for i, name := range names {
+		if received[name] == 0 {
+			t.Errorf("%v: no requests were received", name)
+		}
+		if identities[i].Count != received[name] {
+			t.Errorf("%v: expected %v requests, but got %v",
+				name, received[name], identities[i].Count)
+		}
+	}
+}
+
+func TestBombardierSignsRequests(t *testing.T) {
+	testAllClients(t, testBombardierSignsRequests)
+}
+
+func testBombardierSignsRequests(clientType ClientTyp, t *testing.T) {
+	var invalid uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mac := hmac.New(sha256.New, []byte("secret"))
+			mac.Write([]byte(r.Method + "\n" + r.RequestURI + "\n" +
+				r.Header.Get("X-Timestamp") + "\n"))
+			mac.Write(body)
+			sig := hex.EncodeToString(mac.Sum(nil))
+			if r.Header.Get("X-Signature") != sig {
+				atomic.AddUint64(&invalid, 1)
+				rw.WriteHeader(http.StatusUnauthorized)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	b, e := NewBombardier(Config{
+		numConns:   defaultNumberOfConns,
+		numReqs:    &numReqs,
+		url:        s.URL + "/signed?q=1",
+		headers:    new(HeadersList),
+		timeout:    defaultTimeout,
+		method:     "POST",
+		body:       "payload",
+		hmacSHA256: "secret,X-Signature",
+		clientType: clientType,
+		format:     KnownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.DisableOutput()
+	b.Bombard()
+	if b.req2xx != numReqs || invalid != 0 {
+		t.Errorf("expected %v valid signatures, but got %v invalid ones",
+			numReqs, invalid)
+	}
+}
+
+func TestBombardierKeepsCookies(t *testing.T) {
+	testAllClients(t, testBombardierKeepsCookies)
+}
+
+func testBombardierKeepsCookies(clientType ClientTyp, t *testing.T) {
+	var (
+		mu                      sync.Mutex
+		sessions, withoutCookie uint64
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			if _, err := r.Cookie("session"); err == nil {
+				return
+			}
+			withoutCookie++
+			sessions++
+			http.SetCookie(rw, &http.Cookie{
+				Name:  "session",
+				Value: strconv.FormatUint(sessions, decBase),
+			})
+		}),
+	)
+	defer s.Close()
+	expectations := []struct {
+		cookies  string
+		numConns uint64
+		check    func(numReqs uint64) bool
+	}{
+		{"", 1, func(numReqs uint64) bool {
+			return withoutCookie == numReqs
+		}},
+		{cookiesShared, 1, func(numReqs uint64) bool {
+			return sessions == 1 && withoutCookie == 1
+		}},
+		{cookiesPerWorker, 4, func(numReqs uint64) bool {
+			return sessions <= 4 && withoutCookie == sessions
+		}},
+	}
+	for _, e := range expectations {
+		sessions, withoutCookie = 0, 0
+		numReqs := uint64(40)
+		b, err := NewBombardier(Config{
+			numConns:   e.numConns,
+			numReqs:    &numReqs,
+			url:        s.URL,
+			headers:    new(HeadersList),
+			timeout:    defaultTimeout,
+			method:     "GET",
+			cookies:    e.cookies,
+			clientType: clientType,
+			format:     KnownFormat("plain-text"),
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		b.DisableOutput()
+		b.Bombard()
+		mu.Lock()
+		if !e.check(numReqs) {
+			t.Errorf("%q: unexpected number of sessions(%v) and requests "+
+				"without cookie(%v)", e.cookies, sessions, withoutCookie)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestBombardierRunsScenario(t *testing.T) {
+	testAllClients(t, testBombardierRunsScenario)
+}
+
+func testBombardierRunsScenario(clientType ClientTyp, t *testing.T) {
+	var logins, profiles, updates, unauthorized uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == "POST" && r.URL.Path == "/login":
+				n := atomic.AddUint64(&logins, 1)
+				rw.Header().Set("X-Session", "s"+strconv.FormatUint(n, decBase))
+				_, _ = rw.Write([]byte(`{"token":"t` +
+					strconv.FormatUint(n, decBase) + `","user":{"id":42}}`))
+			case r.Method == "GET" && r.URL.Path == "/users/42":
+				if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer t") {
+					atomic.AddUint64(&unauthorized, 1)
+					rw.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				atomic.AddUint64(&profiles, 1)
+				_, _ = rw.Write([]byte(`<p>version=7</p>`))
+			case r.Method == "PUT" && r.URL.Path == "/users/42":
+				body, _ := ioutil.ReadAll(r.Body)
+				session := r.Header.Get("X-Session")
+				if session == "" || string(body) != `{"version":7}` {
+					atomic.AddUint64(&unauthorized, 1)
+					rw.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				atomic.AddUint64(&updates, 1)
+			default:
+				rw.WriteHeader(http.StatusNotFound)
+			}
+		}),
+	)
+	defer s.Close()
+	dir, err := ioutil.TempDir("", "bombardier-scenario")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := writeTestScenario(t, dir, `{"steps":[
+		{"name":"login","method":"POST","url":"/login","body":"{}",
+		 "extract":[
+			{"var":"token","json":"token"},
+			{"var":"id","json":"user.id"},
+			{"var":"session","header":"X-Session"}
+		 ]},
+		{"name":"profile","url":"/users/${id}",
+		 "headers":["Authorization: Bearer ${token}"],
+		 "extract":[{"var":"version","regex":"version=(\\d+)"}]},
+		{"name":"update","method":"PUT","url":"/users/${id}",
+		 "headers":["X-Session: ${session}"],
+		 "body":"{\"version\":${version}}"},
+		{"name":"missing","url":"/missing",
+		 "extract":[{"var":"v","header":"X-Missing"}]}
+	]}`)
+	numReqs := uint64(20)
+	b, e := NewBombardier(Config{
+		numConns:     4,
+		numReqs:      &numReqs,
+		url:          s.URL,
+		headers:      new(HeadersList),
+		timeout:      defaultTimeout,
+		method:       "GET",
+		scenarioPath: path,
+		clientType:   clientType,
+		format:       KnownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.DisableOutput()
+	b.Bombard()
+	if logins != numReqs || profiles != numReqs || updates != numReqs ||
+		unauthorized != 0 {
+		t.Errorf("expected %v iterations, but got logins(%v), profiles(%v), "+
+			"updates(%v), unauthorized(%v)",
+			numReqs, logins, profiles, updates, unauthorized)
+	}
+	info := b.GatherInfo()
+	ss := info.Result.Scenario
+	if ss == nil {
+		t.Fatal("expected scenario statistics")
+	}
+	// Last step never succeeds, so every iteration is aborted
+	if ss.Completed != 0 || ss.Aborted != numReqs {
+		t.Errorf("expected %v aborted iterations, but got %v completed "+
+			"and %v aborted", numReqs, ss.Completed, ss.Aborted)
+	}
+	for i, step := range ss.Steps {
+		expectedErrors := uint64(0)
+		if i == len(ss.Steps)-1 {
+			expectedErrors = numReqs
+		}
+		if step.Count != numReqs || step.Errors != expectedErrors {
+			t.Errorf("%v: expected %v requests and %v errors, but got %v "+
+				"and %v", step.Name, numReqs, expectedErrors, step.Count,
+				step.Errors)
+		}
+	}
+	if info.Result.Req2XX != 3*numReqs || info.Result.Req4XX != numReqs {
+		t.Errorf("unexpected status codes: 2xx - %v, 4xx - %v",
+			info.Result.Req2XX, info.Result.Req4XX)
+	}
+}
+
+func TestBombardierThinkTimeAndPacing(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	thinkTime := func(spec string) *ThinkTime {
+		tt := new(ThinkTime)
+		if err := tt.Set(spec); err != nil {
+			t.Fatal(err)
+		}
+		return tt
+	}
+	numReqs, testDuration := uint64(4), time.Second
+	expectations := []struct {
+		thinkTime *ThinkTime
+		pacing    time.Duration
+		numReqs   *uint64
+		duration  *time.Duration
+		min, max  time.Duration
+	}{
+		// Workers don't wait after the last request
+		{thinkTime("100ms"), 0, &numReqs, nil,
+			300 * time.Millisecond, 2 * time.Second},
+		{nil, 100 * time.Millisecond, &numReqs, nil,
+			300 * time.Millisecond, 2 * time.Second},
+		{thinkTime("50ms"), 100 * time.Millisecond, &numReqs, nil,
+			300 * time.Millisecond, 2 * time.Second},
+		// Timed tests end on time regardless of think time and pacing
+		{thinkTime("1h"), 0, nil, &testDuration,
+			testDuration, 3 * testDuration},
+		{nil, time.Hour, nil, &testDuration,
+			testDuration, 3 * testDuration},
+	}
+	for _, e := range expectations {
+		b, err := NewBombardier(Config{
+			numConns:   1,
+			numReqs:    e.numReqs,
+			duration:   e.duration,
+			url:        s.URL,
+			headers:    new(HeadersList),
+			timeout:    defaultTimeout,
+			method:     "GET",
+			thinkTime:  e.thinkTime,
+			pacing:     e.pacing,
+			clientType: fhttp,
+			format:     KnownFormat("plain-text"),
+		})
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		b.DisableOutput()
+		b.Bombard()
+		if b.timeTaken < e.min || b.timeTaken > e.max {
+			t.Errorf("%v, %v: expected test to take from %v to %v, "+
+				"but it took %v", e.thinkTime, e.pacing, e.min, e.max,
+				b.timeTaken)
+		}
+	}
+}
+
+func TestBombardierSendsForm(t *testing.T) {
+	testAllClients(t, testBombardierSendsForm)
+}
+
+func testBombardierSendsForm(clientType ClientTyp, t *testing.T) {
+	expectedFile, err := ioutil.ReadFile("testbody.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var valid, invalid uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if err := r.ParseMultipartForm(1 << 20); err != nil &&
+				err != http.ErrNotMultipart {
+				atomic.AddUint64(&invalid, 1)
+				return
+			}
+			if r.PostForm.Get("name") != "bombardier" {
+				atomic.AddUint64(&invalid, 1)
+				return
+			}
+			if r.MultipartForm != nil {
+				file, _, ferr := r.FormFile("file")
+				if ferr != nil {
+					atomic.AddUint64(&invalid, 1)
+					return
+				}
+				content, _ := ioutil.ReadAll(file)
+				file.Close()
+				if !bytes.Equal(content, expectedFile) {
+					atomic.AddUint64(&invalid, 1)
+					return
+				}
+			}
+			atomic.AddUint64(&valid, 1)
+		}),
+	)
+	defer s.Close()
+	forms := []*FormFields{
+		{{key: "name", value: "bombardier"}},
+		{
+			{key: "name", value: "bombardier"},
+			{key: "file", value: "testbody.txt", file: true},
+		},
+	}
+	for _, form := range forms {
+		for _, stream := range []bool{false, true} {
+			valid, invalid = 0, 0
+			numReqs := uint64(10)
+			b, e := NewBombardier(Config{
+				numConns:   2,
+				numReqs:    &numReqs,
+				url:        s.URL,
+				headers:    new(HeadersList),
+				timeout:    defaultTimeout,
+				method:     "POST",
+				form:       form,
+				stream:     stream,
+				clientType: clientType,
+				format:     KnownFormat("plain-text"),
+			})
+			if e != nil {
+				t.Error(e)
+				return
+			}
+			b.DisableOutput()
+			b.Bombard()
+			if valid != numReqs || invalid != 0 {
+				t.Errorf("%v(stream: %v): expected %v valid forms, but got "+
+					"%v valid and %v invalid", form.Entries(), stream,
+					numReqs, valid, invalid)
+			}
+		}
+	}
+}
+
+func TestBombardierCompression(t *testing.T) {
+	testAllClients(t, testBombardierCompression)
+}
+
+func testBombardierCompression(clientType ClientTyp, t *testing.T) {
+	requestBody := strings.Repeat("request ", 64)
+	responseBody := strings.Repeat("response ", 128)
+	var valid, invalid uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			var body []byte
+			gr, err := gzip.NewReader(r.Body)
+			if err == nil {
+				body, err = ioutil.ReadAll(gr)
+			}
+			if err != nil || r.Header.Get("Content-Encoding") != "gzip" ||
+				string(body) != requestBody {
+				atomic.AddUint64(&invalid, 1)
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			atomic.AddUint64(&valid, 1)
+			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+				_, _ = rw.Write([]byte(responseBody))
+				return
+			}
+			rw.Header().Set("Content-Encoding", "gzip")
+			gw := gzip.NewWriter(rw)
+			_, _ = gw.Write([]byte(responseBody))
+			_ = gw.Close()
+		}),
+	)
+	defer s.Close()
+	for _, stream := range []bool{false, true} {
+		valid, invalid = 0, 0
+		numReqs := uint64(10)
+		b, e := NewBombardier(Config{
+			numConns:       2,
+			numReqs:        &numReqs,
+			url:            s.URL,
+			headers:        new(HeadersList),
+			timeout:        defaultTimeout,
+			method:         "POST",
+			body:           requestBody,
+			stream:         stream,
+			compressBody:   encodingGzip,
+			acceptEncoding: "gzip",
+			decompress:     true,
+			clientType:     clientType,
+			format:         KnownFormat("plain-text"),
+		})
+		if e != nil {
+			t.Error(e)
+			return
+		}
+		b.DisableOutput()
+		b.Bombard()
+		if valid != numReqs || invalid != 0 {
+			t.Errorf("stream(%v): expected %v valid bodies, but got %v "+
+				"valid and %v invalid", stream, numReqs, valid, invalid)
+		}
+		res := b.GatherInfo().Result
+		d := res.Decompression
+		if d == nil {
+			t.Fatal("expected decompression statistics")
+		}
+		expected := numReqs * uint64(len(responseBody))
+		if d.Decompressed != expected || d.Compressed == 0 ||
+			d.Compressed >= expected || d.Errors != 0 {
+			t.Errorf("stream(%v): unexpected decompression statistics %+v",
+				stream, *d)
+		}
+		// Wire byte count includes headers of the responses alongside
+		// with their compressed bodies
+		if uint64(res.BytesRead) <= d.Compressed {
+			t.Errorf("stream(%v): expected more than %v bytes read, but "+
+				"got %v", stream, d.Compressed, res.BytesRead)
+		}
+	}
+}
+
+func TestBombardierPipelining(t *testing.T) {
+	var reqsReceived, connsOpened uint64
+	s := httptest.NewUnstartedServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			atomic.AddUint64(&reqsReceived, 1)
+			time.Sleep(time.Millisecond)
+		}),
+	)
+	s.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddUint64(&connsOpened, 1)
+		}
+	}
+	s.Start()
+	defer s.Close()
+	numReqs := uint64(200)
+	b, e := NewBombardier(Config{
+		numConns:   2,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(HeadersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		pipeline:   4,
+		clientType: fhttp,
+		format:     KnownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.DisableOutput()
+	b.Bombard()
+	if reqsReceived != numReqs {
+		t.Errorf("expected %v requests, but got %v", numReqs, reqsReceived)
+	}
+	if connsOpened > 2 {
+		t.Errorf("expected at most 2 connections, but got %v", connsOpened)
+	}
+	info := b.GatherInfo()
+	p := info.Result.Pipeline
+	if p == nil {
+		t.Fatal("expected pipeline statistics")
+	}
+	if p.MaxDepth < 2 || p.Overflows != 0 || p.Stopped != 0 {
+		t.Errorf("unexpected pipeline statistics %+v", *p)
+	}
+	// Pipelined requests aren't told apart, but connections must still
+	// be reported as reused
+	c := info.Result.Connections
+	if c == nil {
+		t.Fatal("expected connection statistics")
+	}
+	if c.Opened != connsOpened || c.Reused == 0 || c.ClosedByServer != 0 {
+		t.Errorf("unexpected connection statistics %+v for %v connections",
+			*c, connsOpened)
+	}
+	if b.req2xx != numReqs {
+		t.Errorf("expected %v successful requests, but got %v",
+			numReqs, b.req2xx)
+	}
+}
+
+func TestBombardierLimitsConnLifetime(t *testing.T) {
+	testAllClients(t, testBombardierLimitsConnLifetime)
+}
+
+func testBombardierLimitsConnLifetime(clientType ClientTyp, t *testing.T) {
+	var connsOpened uint64
+	s := httptest.NewUnstartedServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	s.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddUint64(&connsOpened, 1)
+		}
+	}
+	s.Start()
+	defer s.Close()
+	numReqs := uint64(20)
+	b, e := NewBombardier(Config{
+		numConns:        2,
+		numReqs:         &numReqs,
+		url:             s.URL,
+		headers:         new(HeadersList),
+		timeout:         defaultTimeout,
+		method:          "GET",
+		maxConnRequests: 5,
+		clientType:      clientType,
+		format:          KnownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.DisableOutput()
+	b.Bombard()
+	if b.req2xx != numReqs {
+		t.Errorf("expected %v successful requests, but got %v",
+			numReqs, b.req2xx)
+	}
+	// Each of the workers reconnects after 5 requests
+	if connsOpened < 4 || connsOpened > 6 {
+		t.Errorf("expected 4 to 6 connections, but got %v", connsOpened)
+	}
+	c := b.GatherInfo().Result.Connections
+	if c == nil {
+		t.Fatal("expected connection statistics")
+	}
+	if c.Opened != connsOpened {
+		t.Errorf("expected %v connections opened, but got %v",
+			connsOpened, c.Opened)
+	}
+	if c.Reused != numReqs-connsOpened {
+		t.Errorf("expected %v reused connections, but got %v",
+			numReqs-connsOpened, c.Reused)
+	}
+	if c.ClosedByServer != 0 {
+		t.Errorf("expected no connections closed by server, but got %v",
+			c.ClosedByServer)
+	}
+}
+
+func TestBombardierMultiplexesStreams(t *testing.T) {
+	var connsOpened, reqsReceived, http2Reqs uint64
+	s := httptest.NewUnstartedServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			atomic.AddUint64(&reqsReceived, 1)
+			if r.ProtoMajor == 2 {
+				atomic.AddUint64(&http2Reqs, 1)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}),
+	)
+	s.EnableHTTP2 = true
+	s.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddUint64(&connsOpened, 1)
+		}
+	}
+	s.StartTLS()
+	defer s.Close()
+	numReqs := uint64(100)
+	b, e := NewBombardier(Config{
+		numConns:       2,
+		numReqs:        &numReqs,
+		url:            s.URL,
+		headers:        new(HeadersList),
+		timeout:        defaultTimeout,
+		method:         "GET",
+		insecure:       true,
+		streamsPerConn: 5,
+		clientType:     nhttp2,
+		format:         KnownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.DisableOutput()
+	b.Bombard()
+	if reqsReceived != numReqs || http2Reqs != numReqs {
+		t.Errorf("expected %v HTTP/2 requests, but got %v of %v",
+			numReqs, http2Reqs, reqsReceived)
+	}
+	if connsOpened != 2 {
+		t.Errorf("expected 2 connections, but got %v", connsOpened)
+	}
+	st := b.GatherInfo().Result.Streams
+	if st == nil {
+		t.Fatal("expected stream statistics")
+	}
+	if st.MaxInFlight < 2 || st.MaxInFlight > 5 {
+		t.Errorf("expected 2 to 5 streams in flight, but got %v",
+			st.MaxInFlight)
+	}
+}
+
+func TestBombardierLimitsBandwidth(t *testing.T) {
+	testAllClients(t, testBombardierLimitsBandwidth)
+}
+
+func testBombardierLimitsBandwidth(clientType ClientTyp, t *testing.T) {
+	response := bytes.Repeat([]byte("a"), 10000)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_, _ = rw.Write(response)
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(3)
+	bandwidth := new(BandwidthLimit)
+	if err := bandwidth.Set("100KB"); err != nil {
+		t.Fatal(err)
+	}
+	b, e := NewBombardier(Config{
+		numConns:    1,
+		numReqs:     &numReqs,
+		url:         s.URL,
+		headers:     new(HeadersList),
+		timeout:     defaultTimeout,
+		method:      "GET",
+		bandwidth:   bandwidth,
+		connLatency: time.Millisecond,
+		clientType:  clientType,
+		format:      KnownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.DisableOutput()
+	start := time.Now()
+	b.Bombard()
+	// 3 responses of 10KB each take at least 300ms at 100KB/s
+	if elapsed := time.Since(start); elapsed < 280*time.Millisecond {
+		t.Errorf("expected test to take 300ms, but it took %v", elapsed)
+	}
+	if b.req2xx != numReqs {
+		t.Errorf("expected %v successful requests, but got %v",
+			numReqs, b.req2xx)
+	}
+	if b.bytesRead < int64(numReqs)*int64(len(response)) {
+		t.Errorf("expected at least %v bytes read, but got %v",
+			int64(numReqs)*int64(len(response)), b.bytesRead)
+	}
+}
+
+func TestBombardierSamplesResponses(t *testing.T) {
+	testAllClients(t, testBombardierSamplesResponses)
+}
+
+func testBombardierSamplesResponses(clientType ClientTyp, t *testing.T) {
+	var reqs uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if atomic.AddUint64(&reqs, 1)%2 == 0 {
+				rw.Header().Set("X-Reason", "gone")
+				rw.WriteHeader(http.StatusNotFound)
+				_, _ = rw.Write([]byte("no such thing"))
+				return
+			}
+			_, _ = rw.Write([]byte("ok"))
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(20)
+	b, e := NewBombardier(Config{
+		numConns:        2,
+		numReqs:         &numReqs,
+		url:             s.URL,
+		headers:         new(HeadersList),
+		timeout:         defaultTimeout,
+		method:          "GET",
+		sampleResponses: 2,
+		clientType:      clientType,
+		format:          KnownFormat("json"),
+		printResult:     true,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.DisableOutput()
+	b.Bombard()
+	out := new(bytes.Buffer)
+	b.RedirectOutputTo(out)
+	b.PrintStats()
+	var output struct {
+		Result struct {
+			Samples []struct {
+				Status    int
+				Seen      uint64
+				Responses []struct {
+					Status  int
+					Headers []struct{ Key, Value string }
+					Body    string
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &output); err != nil {
+		t.Fatal(err, out.String())
+	}
+	samples := output.Result.Samples
+	if len(samples) != 2 {
+		t.Fatalf("expected samples of 2 status codes, but got %v",
+			len(samples))
+	}
+	expectations := []struct {
+		status int
+		body   string
+	}{
+		{200, "ok"},
+		{404, "no such thing"},
+	}
+	for i, e := range expectations {
+		g := samples[i]
+		if g.Status != e.status || g.Seen != 10 || len(g.Responses) != 4 {
+			t.Errorf("expected 4 of 10 responses with %v, but got %v of %v"+
+				" with %v", e.status, len(g.Responses), g.Seen, g.Status)
+			continue
+		}
+		for _, r := range g.Responses {
+			if r.Status != e.status || r.Body != e.body {
+				t.Errorf("expected %v with %q, but got %v with %q",
+					e.status, e.body, r.Status, r.Body)
+			}
+		}
+	}
+	found := false
+	for _, h := range samples[1].Responses[0].Headers {
+		if strings.EqualFold(h.Key, "X-Reason") && h.Value == "gone" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected X-Reason header in %+v",
+			samples[1].Responses[0].Headers)
+	}
+}
+
+func TestBombardierWritesSamples(t *testing.T) {
+	dir, err := ioutil.TempDir("", "samples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusTeapot)
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	b, e := NewBombardier(Config{
+		numConns:        1,
+		numReqs:         &numReqs,
+		url:             s.URL,
+		headers:         new(HeadersList),
+		timeout:         defaultTimeout,
+		method:          "GET",
+		sampleResponses: 1,
+		sampleDir:       dir,
+		clientType:      fhttp,
+		format:          KnownFormat("plain-text"),
+		printResult:     true,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.DisableOutput()
+	b.Bombard()
+	files, err := filepath.Glob(filepath.Join(dir, "status-418-*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("expected 2 samples, but got %v", files)
+	}
+	out := new(bytes.Buffer)
+	b.RedirectOutputTo(out)
+	b.PrintStats()
+	summary := "\n  Response samples:\n    418 - 2 kept of 10\n    written to " +
+		dir
+	if !strings.Contains(out.String(), summary) {
+		t.Errorf("expected %q in %q", summary, out.String())
+	}
+}
+
+func TestBombardierWritesTraceLog(t *testing.T) {
+	testAllClients(t, testBombardierWritesTraceLog)
+}
+
+func testBombardierWritesTraceLog(clientType ClientTyp, t *testing.T) {
+	dir, err := ioutil.TempDir("", "trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusTeapot)
+			_, _ = rw.Write([]byte("short and stout"))
+		}),
+	)
+	defer s.Close()
+	path := filepath.Join(dir, "trace.ndjson")
+	numReqs := uint64(10)
+	b, e := NewBombardier(Config{
+		numConns:   2,
+		numReqs:    &numReqs,
+		url:        s.URL + "/teapot",
+		headers:    new(HeadersList),
+		timeout:    defaultTimeout,
+		method:     "POST",
+		body:       "tea",
+		traceLog:   path,
+		clientType: clientType,
+		format:     KnownFormat("json"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.DisableOutput()
+	b.Bombard()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != int(numReqs) {
+		t.Fatalf("expected %v entries, but got %v", numReqs, len(lines))
+	}
+	workers := make(map[uint64]bool)
+	for _, line := range lines {
+		var entry TraceEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		workers[entry.Worker] = true
+		if entry.Method != "POST" || entry.URL != s.URL+"/teapot" ||
+			entry.Status != http.StatusTeapot || entry.Error != "" {
+			t.Errorf("unexpected entry %+v", entry)
+		}
+		if entry.BytesOut <= int64(len("tea")) ||
+			entry.BytesIn <= int64(len("short and stout")) {
+			t.Errorf("unexpected sizes in %+v", entry)
+		}
+		if entry.Time.IsZero() {
+			t.Errorf("expected time in %+v", entry)
+		}
+	}
+	if len(workers) != 2 {
+		t.Errorf("expected entries of 2 workers, but got %v", workers)
+	}
+	out := new(bytes.Buffer)
+	b.RedirectOutputTo(out)
+	b.PrintStats()
+	if !json.Valid(out.Bytes()) {
+		t.Fatalf("expected valid JSON, but got %q", out.String())
+	}
+	result := `"traceLog":{"written":10,"dropped":0}`
+	if !strings.Contains(out.String(), result) {
+		t.Errorf("expected %q in %q", result, out.String())
+	}
+}
+
+func TestBombardierClassifiesErrors(t *testing.T) {
+	testAllClients(t, testBombardierClassifiesErrors)
+}
+
+func testBombardierClassifiesErrors(clientType ClientTyp, t *testing.T) {
+	// Nothing listens on the port once the listener is closed
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	numReqs := uint64(5)
+	b, e := NewBombardier(Config{
+		numConns:   1,
+		numReqs:    &numReqs,
+		url:        "http://" + addr,
+		headers:    new(HeadersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		clientType: clientType,
+		format:     KnownFormat("json"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.DisableOutput()
+	b.Bombard()
+	errs := b.errors.ByFrequency()
+	if len(errs) != 1 || errs[0].class != internal.ErrorRefused ||
+		errs[0].count != numReqs {
+		t.Fatalf("expected %v refused connections, but got %v", numReqs, errs)
+	}
+	out := new(bytes.Buffer)
+	b.RedirectOutputTo(out)
+	b.PrintStats()
+	if !json.Valid(out.Bytes()) {
+		t.Fatalf("expected valid JSON, but got %q", out.String())
+	}
+	result := `"errors":[{"class":"connection refused","description":`
+	if !strings.Contains(out.String(), result) {
+		t.Errorf("expected %q in %q", result, out.String())
+	}
+}
+
+func TestBombardierErrorTimeline(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	numReqs := uint64(5)
+	for _, format := range []string{"plain-text", "json"} {
+		b, e := NewBombardier(Config{
+			numConns:       1,
+			numReqs:        &numReqs,
+			url:            "http://" + addr,
+			headers:        new(HeadersList),
+			timeout:        defaultTimeout,
+			method:         "GET",
+			errorHistogram: true,
+			clientType:     fhttp,
+			format:         KnownFormat(format),
+			printResult:    true,
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+		b.DisableOutput()
+		b.Bombard()
+		out := new(bytes.Buffer)
+		b.RedirectOutputTo(out)
+		b.PrintStats()
+		expected := []string{"\n      first at ", "\n      per second: 5"}
+		if format == "json" {
+			expected = []string{
+				`"errorHistogram":true`, `"firstSeenSeconds":`, `"perSecond":[5]`,
+			}
+			if !json.Valid(out.Bytes()) {
+				t.Fatalf("expected valid JSON, but got %q", out.String())
+			}
+		}
+		for _, s := range expected {
+			if !strings.Contains(out.String(), s) {
+				t.Errorf("expected %q in %q", s, out.String())
+			}
+		}
+	}
+}
+
+func TestBombardierCountsStatusCodes(t *testing.T) {
+	testAllClients(t, testBombardierCountsStatusCodes)
+}
+
+func testBombardierCountsStatusCodes(clientType ClientTyp, t *testing.T) {
+	var reqs uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			switch atomic.AddUint64(&reqs, 1) % 3 {
+			case 0:
+				rw.WriteHeader(http.StatusTooManyRequests)
+			case 1:
+				rw.WriteHeader(http.StatusNotFound)
+			default:
+				rw.WriteHeader(http.StatusUnauthorized)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(9)
+	for _, format := range []string{"plain-text", "json"} {
+		atomic.StoreUint64(&reqs, 0)
+		b, e := NewBombardier(Config{
+			numConns:        1,
+			numReqs:         &numReqs,
+			url:             s.URL,
+			headers:         new(HeadersList),
+			timeout:         defaultTimeout,
+			method:          "GET",
+			statusLatencies: true,
+			clientType:      clientType,
+			format:          KnownFormat(format),
+			printResult:     true,
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+		b.DisableOutput()
+		b.Bombard()
+		out := new(bytes.Buffer)
+		b.RedirectOutputTo(out)
+		b.PrintStats()
+		expected := []string{
+			"\n    401 - 3, latency ",
+			"\n    404 - 3, latency ",
+			"\n    429 - 3, latency ",
+		}
+		if format == "json" {
+			expected = []string{
+				`"statusCodes":[{"code":401,"count":3,"latency":{`,
+				`{"code":404,"count":3,"latency":{`,
+				`{"code":429,"count":3,"latency":{`,
+			}
+			if !json.Valid(out.Bytes()) {
+				t.Fatalf("expected valid JSON, but got %q", out.String())
+			}
+		}
+		for _, s := range expected {
+			if !strings.Contains(out.String(), s) {
+				t.Errorf("expected %q in %q", s, out.String())
+			}
+		}
+	}
+}
+
+func TestBombardierSplitsLatenciesByOutcome(t *testing.T) {
+	testAllClients(t, testBombardierSplitsLatenciesByOutcome)
+}
+
+func testBombardierSplitsLatenciesByOutcome(
+	clientType ClientTyp, t *testing.T,
+) {
+	var reqs uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			switch atomic.AddUint64(&reqs, 1) % 3 {
+			case 0:
+				rw.WriteHeader(http.StatusNotFound)
+			case 1:
+				rw.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(9)
+	b, e := NewBombardier(Config{
+		numConns:   1,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(HeadersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		clientType: clientType,
+		format:     KnownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.DisableOutput()
+	b.Bombard()
+	for _, e := range []struct {
+		name      string
+		latencies *uhist.Histogram
+	}{
+		{"successful", b.successLatencies},
+		{"4xx", b.latencies4xx},
+		{"5xx", b.latencies5xx},
+	} {
+		if c := e.latencies.Count(); c == 0 {
+			t.Errorf("expected latencies of %v requests", e.name)
+		}
+	}
+	if c := b.errorLatencies.Count(); c != 0 {
+		t.Errorf("expected no latencies of failed requests, but got %v", c)
+	}
+	out := new(bytes.Buffer)
+	b.RedirectOutputTo(out)
+	b.PrintStats()
+	latency := strings.Index(out.String(), "\n  Latency ")
+	failed := strings.Index(out.String(), "\n  Lat. 4xx ")
+	if latency < 0 || failed < latency ||
+		!strings.Contains(out.String(), "\n  Lat. 5xx ") ||
+		!strings.Contains(out.String(), "\n  Lat. all ") {
+		t.Errorf("expected latencies of successful requests first, "+
+			"but got %q", out.String())
+	}
+}
+
+func TestBombardierErrorLatencies(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	numReqs := uint64(5)
+	for _, format := range []string{"plain-text", "json"} {
+		b, e := NewBombardier(Config{
+			numConns:    1,
+			numReqs:     &numReqs,
+			url:         "http://" + addr,
+			headers:     new(HeadersList),
+			timeout:     defaultTimeout,
+			method:      "GET",
+			clientType:  fhttp,
+			format:      KnownFormat(format),
+			printResult: true,
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+		b.DisableOutput()
+		b.Bombard()
+		out := new(bytes.Buffer)
+		b.RedirectOutputTo(out)
+		b.PrintStats()
+		expected := []string{
+			"There wasn't enough data to compute statistics for " +
+				"latencies of successful requests.",
+			"\n  Lat. errors ",
+		}
+		if format == "json" {
+			expected = []string{`"failedLatency":{"errors":{"mean":`}
+			if !json.Valid(out.Bytes()) {
+				t.Fatalf("expected valid JSON, but got %q", out.String())
+			}
+			if strings.Contains(out.String(), `"successLatency"`) {
+				t.Errorf("expected no successLatency in %q", out.String())
+			}
+		}
+		for _, s := range expected {
+			if !strings.Contains(out.String(), s) {
+				t.Errorf("expected %q in %q", s, out.String())
+			}
+		}
+	}
+}
+
+func TestBombardierClassifiesErrorsPerLocalAddr(t *testing.T) {
+	testAllClients(t, testBombardierClassifiesErrorsPerLocalAddr)
+}
+
+func testBombardierClassifiesErrorsPerLocalAddr(
+	clientType ClientTyp, t *testing.T,
+) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	numReqs := uint64(10)
+	localAddrs := LocalAddrList{"127.0.0.1", "127.0.0.2"}
+	b, e := NewBombardier(Config{
+		numConns:   2,
+		numReqs:    &numReqs,
+		url:        "http://" + addr,
+		headers:    new(HeadersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		localAddrs: &localAddrs,
+		clientType: clientType,
+		format:     KnownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.DisableOutput()
+	b.Bombard()
+	seen := make(map[string]uint64)
+	for _, ewc := range b.errors.ByFrequency() {
+		if ewc.class != internal.ErrorRefused {
+			t.Errorf("expected refused connection, but got %v", ewc)
+			continue
+		}
+		for _, la := range localAddrs {
+			if strings.Contains(ewc.error, "local address "+la+": ") {
+				seen[la] += ewc.count
+			}
+		}
+	}
+	if len(seen) != 2 || seen["127.0.0.1"]+seen["127.0.0.2"] != numReqs {
+		t.Errorf("expected %v refused connections from both addresses, "+
+			"but got %v", numReqs, b.errors.ByFrequency())
+	}
+}
@@ -2,21 +2,193 @@ package bombardier
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
-// ReadClientCert - helper function to read Client certificate
-// from pem formatted certPath and keyPath files
+// ReadClientCert - helper function to read Client certificates
+// from pem formatted certPath and keyPath files (see ClientCertPaths)
 func ReadClientCert(certPath, keyPath string) ([]tls.Certificate, error) {
-	if certPath != "" && keyPath != "" {
+	certPaths, keyPaths, err := ClientCertPaths(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+	var certs []tls.Certificate
+	for i := range certPaths {
 		// load keypair
-		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		cert, err := tls.LoadX509KeyPair(certPaths[i], keyPaths[i])
 		if err != nil {
 			return nil, err
 		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+var clientCertExts = []string{".crt", ".cert", ".pem"}
 
-		return []tls.Certificate{cert}, nil
+// ClientCertPaths - helper function to list certificate and key file
+// pairs. certPath and keyPath are either comma-separated lists of the
+// same length or directories (keyPath defaults to certPath then), in
+// which case every *.key file is paired with the certificate that has
+// the same name and .crt, .cert or .pem extension
+func ClientCertPaths(certPath, keyPath string) ([]string, []string, error) {
+	if certPath == "" {
+		return nil, nil, nil
+	}
+	if fi, err := os.Stat(certPath); err == nil && fi.IsDir() {
+		return clientCertPathsFromDir(certPath, keyPath)
+	}
+	if keyPath == "" {
+		return nil, nil, nil
 	}
-	return nil, nil
+	certPaths := strings.Split(certPath, ",")
+	keyPaths := strings.Split(keyPath, ",")
+	if len(certPaths) != len(keyPaths) {
+		return nil, nil, errCertKeyCountMismatch
+	}
+	return certPaths, keyPaths, nil
+}
+
+func clientCertPathsFromDir(
+	certDir, keyDir string,
+) ([]string, []string, error) {
+	if keyDir == "" {
+		keyDir = certDir
+	}
+	keyPaths, err := filepath.Glob(filepath.Join(keyDir, "*.key"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(keyPaths) == 0 {
+		return nil, nil, fmt.Errorf("no keys found in %v", keyDir)
+	}
+	certPaths := make([]string, 0, len(keyPaths))
+	for _, kp := range keyPaths {
+		name := strings.TrimSuffix(filepath.Base(kp), ".key")
+		found := false
+		for _, ext := range clientCertExts {
+			cp := filepath.Join(certDir, name+ext)
+			if _, err = os.Stat(cp); err == nil {
+				certPaths = append(certPaths, cp)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("no certificate found for %v", kp)
+		}
+	}
+	return certPaths, keyPaths, nil
+}
+
+// ReadCACerts - helper function to read pem formatted CA certificates
+// bundle to verify server certificates against
+func ReadCACerts(caCertPath string) (*x509.CertPool, error) {
+	if caCertPath == "" {
+		return nil, nil
+	}
+	pem, err := ioutil.ReadFile(caCertPath)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %v", caCertPath)
+	}
+	return pool, nil
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion - helper function to convert version string
+// (i.e. 1.2) into its identifier, empty string is converted to 0
+func ParseTLSVersion(version string) (uint16, error) {
+	if version == "" {
+		return 0, nil
+	}
+	v, ok := tlsVersions[strings.TrimPrefix(version, "TLS")]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q", version)
+	}
+	return v, nil
+}
+
+// ParseCipherSuites - helper function to convert comma-separated
+// list of cipher suite names into their identifiers
+func ParseCipherSuites(ciphers string) ([]uint16, error) {
+	if ciphers == "" {
+		return nil, nil
+	}
+	known := make(map[string]uint16)
+	for _, cs := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[cs.Name] = cs.ID
+	}
+	var ids []uint16
+	for _, name := range strings.Split(ciphers, ",") {
+		id, ok := known[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+var curves = map[string]tls.CurveID{
+	"X25519": tls.X25519,
+	"P256":   tls.CurveP256,
+	"P384":   tls.CurveP384,
+	"P521":   tls.CurveP521,
+}
+
+// ParseCurves - helper function to convert comma-separated list of
+// elliptic curve names (X25519, P256, P384, P521) into their
+// identifiers
+func ParseCurves(names string) ([]tls.CurveID, error) {
+	if names == "" {
+		return nil, nil
+	}
+	var ids []tls.CurveID
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimPrefix(strings.TrimSpace(name), "Curve")
+		id, ok := curves[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown curve %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// ParseALPN - helper function to split comma-separated list of
+// application protocols
+func ParseALPN(alpn string) []string {
+	if alpn == "" {
+		return nil
+	}
+	var protos []string
+	for _, p := range strings.Split(alpn, ",") {
+		protos = append(protos, strings.TrimSpace(p))
+	}
+	return protos
+}
+
+// OpenKeyLog - helper function to open file TLS secrets should be
+// appended to in NSS key log format, so that captured traffic can be
+// decrypted, i.e. by Wireshark
+func OpenKeyLog(keyLogPath string) (*os.File, error) {
+	return os.OpenFile(keyLogPath,
+		os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
 }
 
 // GenerateTLSConfig - helper function to generate a TLS configuration based on
@@ -26,12 +198,40 @@ func GenerateTLSConfig(c Config) (*tls.Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	rootCAs, err := ReadCACerts(c.caCertPath)
+	if err != nil {
+		return nil, err
+	}
+	// Versions, ciphers and curves are validated by CheckTLSParameters
+	minVersion, _ := ParseTLSVersion(c.tlsMinVersion)
+	maxVersion, _ := ParseTLSVersion(c.tlsMaxVersion)
+	cipherSuites, _ := ParseCipherSuites(c.ciphers)
+	curvePreferences, _ := ParseCurves(c.curves)
 	// Disable gas warning, because InsecureSkipVerify may be set to true
 	// for the purpose of testing
 	/* #nosec */
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: c.insecure,
 		Certificates:       certs,
+		RootCAs:            rootCAs,
+		ServerName:         c.sni,
+		MinVersion:         minVersion,
+		MaxVersion:         maxVersion,
+		CipherSuites:       cipherSuites,
+		CurvePreferences:   curvePreferences,
+		NextProtos:         ParseALPN(c.alpn),
+		ClientSessionCache: NewClientSessionCache(
+			c.tlsResumption, int(c.numConns),
+		),
+	}
+	// Key log is opened last, so that it doesn't leak if any of the
+	// above fails
+	if c.tlsKeyLog != "" {
+		keyLog, err := OpenKeyLog(c.tlsKeyLog)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.KeyLogWriter = keyLog
 	}
 	return tlsConfig, nil
 }
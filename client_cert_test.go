@@ -1,7 +1,20 @@
 package bombardier
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestGenerateTLSConfig(t *testing.T) {
@@ -39,3 +52,196 @@ func TestGenerateTLSConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateTLSConfigWithTLSParameters(t *testing.T) {
+	c, err := GenerateTLSConfig(Config{
+		url:           "https://doesnt.exist.com",
+		sni:           "sni.exist.com",
+		tlsMinVersion: "1.1",
+		tlsMaxVersion: "1.2",
+		ciphers: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256," +
+			"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+		curves: "X25519,P256",
+		alpn:   "h2, http/1.1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ServerName != "sni.exist.com" {
+		t.Errorf("unexpected server name: %v", c.ServerName)
+	}
+	if c.MinVersion != tls.VersionTLS11 || c.MaxVersion != tls.VersionTLS12 {
+		t.Errorf("unexpected versions: %x - %x", c.MinVersion, c.MaxVersion)
+	}
+	ciphers := []uint16{
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	}
+	if !reflect.DeepEqual(c.CipherSuites, ciphers) {
+		t.Errorf("unexpected cipher suites: %v", c.CipherSuites)
+	}
+	curves := []tls.CurveID{tls.X25519, tls.CurveP256}
+	if !reflect.DeepEqual(c.CurvePreferences, curves) {
+		t.Errorf("unexpected curves: %v", c.CurvePreferences)
+	}
+	if protos := []string{"h2", "http/1.1"}; !reflect.DeepEqual(c.NextProtos, protos) {
+		t.Errorf("unexpected application protocols: %v", c.NextProtos)
+	}
+}
+
+func TestGenerateTLSConfigWithCACerts(t *testing.T) {
+	expectations := []struct {
+		caCertPath string
+		errIsNil   bool
+	}{
+		{"testserver.cert", true},
+		{"testbody.txt", false},
+		{"doesnotexist.pem", false},
+	}
+	for _, e := range expectations {
+		c, err := GenerateTLSConfig(Config{
+			url:        "https://doesnt.exist.com",
+			caCertPath: e.caCertPath,
+		})
+		if (err == nil) != e.errIsNil {
+			t.Error(e.caCertPath, err)
+			continue
+		}
+		if err == nil && c.RootCAs == nil {
+			t.Errorf("%v: root CAs weren't set", e.caCertPath)
+		}
+	}
+}
+
+func TestParseTLSParameters(t *testing.T) {
+	for _, v := range []string{"1.4", "SSLv3", "1"} {
+		if _, err := ParseTLSVersion(v); err == nil {
+			t.Errorf("%q shouldn't be a valid TLS version", v)
+		}
+	}
+	if v, err := ParseTLSVersion("TLS1.3"); err != nil || v != tls.VersionTLS13 {
+		t.Errorf("unexpected result: %x, %v", v, err)
+	}
+	if _, err := ParseCipherSuites("TLS_RSA_WITH_NOT_A_CIPHER"); err == nil {
+		t.Error("unknown cipher suite was parsed")
+	}
+	if _, err := ParseCurves("P224"); err == nil {
+		t.Error("unknown curve was parsed")
+	}
+}
+
+func TestGenerateTLSConfigWithKeyLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bombardier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c, err := GenerateTLSConfig(Config{
+		url:       "https://doesnt.exist.com",
+		tlsKeyLog: filepath.Join(dir, "keys.log"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, ok := c.KeyLogWriter.(*os.File)
+	if !ok {
+		t.Fatalf("unexpected key log writer: %v", c.KeyLogWriter)
+	}
+	f.Close()
+	_, err = GenerateTLSConfig(Config{
+		url:       "https://doesnt.exist.com",
+		tlsKeyLog: filepath.Join(dir, "doesnotexist", "keys.log"),
+	})
+	if err == nil {
+		t.Error("expected error for nonexistent directory")
+	}
+	c, err = GenerateTLSConfig(Config{url: "https://doesnt.exist.com"})
+	if err != nil || c.KeyLogWriter != nil {
+		t.Errorf("unexpected key log writer: %v, %v", c.KeyLogWriter, err)
+	}
+}
+
+// writeTestClientCert generates self-signed certificate with name as
+// common name and writes it to dir/name+ext alongside with the key
+// (dir/name.key).
+func writeTestClientCert(t *testing.T, dir, name, ext string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(
+		rand.Reader, template, template, &key.PublicKey, key,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, name+ext),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, name+".key"),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientCertPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bombardier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestClientCert(t, dir, "a", ".crt")
+	writeTestClientCert(t, dir, "b", ".pem")
+
+	certPaths, keyPaths, err := ClientCertPaths(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expCerts := []string{
+		filepath.Join(dir, "a.crt"), filepath.Join(dir, "b.pem"),
+	}
+	expKeys := []string{
+		filepath.Join(dir, "a.key"), filepath.Join(dir, "b.key"),
+	}
+	if !reflect.DeepEqual(certPaths, expCerts) ||
+		!reflect.DeepEqual(keyPaths, expKeys) {
+		t.Errorf("unexpected pairs: %v, %v", certPaths, keyPaths)
+	}
+	certs, err := ReadClientCert(dir, "")
+	if err != nil || len(certs) != 2 {
+		t.Errorf("expected 2 certificates, but got %v (%v)", len(certs), err)
+	}
+
+	certPaths, keyPaths, err = ClientCertPaths("a.crt,b.crt", "a.key,b.key")
+	if err != nil || len(certPaths) != 2 || len(keyPaths) != 2 {
+		t.Errorf("unexpected pairs: %v, %v, %v", certPaths, keyPaths, err)
+	}
+	_, _, err = ClientCertPaths("a.crt,b.crt", "a.key")
+	if err != errCertKeyCountMismatch {
+		t.Errorf("expected %v, but got %v", errCertKeyCountMismatch, err)
+	}
+
+	if err = os.Remove(filepath.Join(dir, "a.crt")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = ClientCertPaths(dir, ""); err == nil {
+		t.Error("expected error for key without certificate")
+	}
+}
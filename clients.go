@@ -1,14 +1,18 @@
 package bombardier
 
 import (
+	"bytes"
 	"crypto/tls"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"strings"
 	"time"
 
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
 	"github.com/valyala/fasthttp"
 	"golang.org/x/net/http2"
 )
@@ -19,6 +23,19 @@ type Client interface {
 
 type BodyStreamProducer func() (io.ReadCloser, error)
 
+// IdleConnCloser is implemented by clients that are able to close
+// connections kept open between requests.
+type IdleConnCloser interface {
+	CloseIdleConnections()
+}
+
+// CloseIdleConnections closes idle connections of c, if it's able to.
+func CloseIdleConnections(c Client) {
+	if ic, ok := c.(IdleConnCloser); ok {
+		ic.CloseIdleConnections()
+	}
+}
+
 type ClientOpts struct {
 	HTTP2 bool
 
@@ -29,18 +46,58 @@ type ClientOpts struct {
 
 	headers     *HeadersList
 	url, method string
+	unixSocket  string
+
+	connectTo   *ConnectToList
+	resolve     *ResolveList
+	spreadAddrs bool
+	localAddrs  *LocalAddrList
+	ipVersion   int
+	proxy       string
 
 	body    *string
 	bodProd BodyStreamProducer
 
 	bytesRead, bytesWritten *int64
+	addrs                   *AddrMap
+	proxyLatencies          *uhist.Histogram
+	tlsStats                *TLSStats
+	identities              *ClientIdentities
+	auth                    Authorizer
+	cookieJar               http.CookieJar
+	decompressor            *Decompressor
+	pipeline                uint64
+	pipelineStats           *PipelineStats
+	connStats               *ConnStats
+	lifetime                *ConnLifetime
+	streams                 *ConnStreams
+	bandwidth               *BandwidthLimit
+	connLatency             time.Duration
+	sampler                 *ResponseSampler
+}
+
+// fasthttpDoer is implemented by both fasthttp.HostClient and
+// fasthttp.PipelineClient.
+type fasthttpDoer interface {
+	Do(req *fasthttp.Request, resp *fasthttp.Response) error
 }
 
 type FasthttpClient struct {
-	client *fasthttp.HostClient
+	client       fasthttpDoer
+	addrs        *AddrMap
+	identities   *ClientIdentities
+	auth         Authorizer
+	jar          http.CookieJar
+	decompressor *Decompressor
+	pipeline     *PipelineStats
+	lifetime     *ConnLifetime
+	sampler      *ResponseSampler
+	trace        func(*TraceEntry)
 
 	headers                  *fasthttp.RequestHeader
+	url                      *url.URL
 	host, requestURI, method string
+	absoluteURI              bool
 
 	body    *string
 	bodProd BodyStreamProducer
@@ -53,26 +110,75 @@ func NewFastHTTPClient(opts *ClientOpts) Client {
 		// opts.url guaranteed to be valid at this point
 		panic(err)
 	}
+	c.url = u
 	c.host = u.Host
 	c.requestURI = u.RequestURI()
-	c.client = &fasthttp.HostClient{
-		Addr:                          u.Host,
-		IsTLS:                         u.Scheme == "https",
-		MaxConns:                      int(opts.maxConns),
-		ReadTimeout:                   opts.timeout,
-		WriteTimeout:                  opts.timeout,
-		DisableHeaderNamesNormalizing: true,
-		TLSConfig:                     opts.tlsConfig,
-		Dial: FasthttpDialFunc(
-			opts.bytesRead, opts.bytesWritten,
-		),
+	// TLS handshake is performed by the dialer, since fasthttp doesn't
+	// honour most of the tls.Config fields.
+	dial := FasthttpDialFunc(opts)
+	if opts.connStats != nil {
+		dial = opts.connStats.Dial(dial)
+	}
+	if opts.pipeline > 0 {
+		c.pipeline = opts.pipelineStats
+		c.client = &fasthttp.PipelineClient{
+			Addr:               u.Host,
+			MaxConns:           int(opts.maxConns),
+			MaxPendingRequests: int(opts.pipeline),
+			ReadTimeout:        opts.timeout,
+			WriteTimeout:       opts.timeout,
+			Dial:               c.pipeline.Dial(dial),
+		}
+	} else {
+		c.client = &fasthttp.HostClient{
+			Addr:                          u.Host,
+			MaxConns:                      int(opts.maxConns),
+			ReadTimeout:                   opts.timeout,
+			WriteTimeout:                  opts.timeout,
+			DisableHeaderNamesNormalizing: true,
+			Dial:                          dial,
+		}
 	}
 	c.headers = HeadersToFastHTTPHeaders(opts.headers)
+	if opts.proxy != "" {
+		proxy, perr := url.Parse(opts.proxy)
+		if perr != nil {
+			// opts.proxy guaranteed to be valid at this point
+			panic(perr)
+		}
+		// Requests forwarded by the proxy must contain absolute URI.
+		if ForwardsThroughProxy(proxy, u) {
+			c.requestURI = u.String()
+			c.absoluteURI = true
+			if auth := ProxyAuthorization(proxy); auth != "" {
+				if c.headers == nil {
+					c.headers = new(fasthttp.RequestHeader)
+				}
+				c.headers.Set("Proxy-Authorization", auth)
+			}
+		}
+	}
 	c.method, c.body = opts.method, opts.body
 	c.bodProd = opts.bodProd
+	c.addrs, c.identities = opts.addrs, opts.identities
+	c.auth, c.jar = opts.auth, opts.cookieJar
+	c.decompressor, c.lifetime = opts.decompressor, opts.lifetime
+	c.sampler = opts.sampler
 	return Client(c)
 }
 
+func (c *FasthttpClient) WithCookieJar(jar http.CookieJar) Client {
+	cc := *c
+	cc.jar = jar
+	return Client(&cc)
+}
+
+func (c *FasthttpClient) WithTrace(trace func(*TraceEntry)) Client {
+	cc := *c
+	cc.trace = trace
+	return Client(&cc)
+}
+
 func (c *FasthttpClient) Do() (
 	code int, usTaken uint64, err error,
 ) {
@@ -96,8 +202,159 @@ func (c *FasthttpClient) Do() (
 		}
 		req.SetBodyStream(bs, -1)
 	}
+	if aerr := c.prepare(req, c.method, c.url, c.body); aerr != nil {
+		fasthttp.ReleaseRequest(req)
+		fasthttp.ReleaseResponse(resp)
+		return 0, 0, aerr
+	}
+
+	code, usTaken, err = c.send(req, resp, c.url)
+	if err == nil && (c.decompressor != nil || c.sampler != nil) {
+		err = c.readBody(resp, ioutil.Discard)
+	}
+	if c.trace != nil {
+		c.traceExchange(req, resp, c.url, c.body != nil, code, usTaken, err)
+	}
+
+	// release resources
+	fasthttp.ReleaseRequest(req)
+	fasthttp.ReleaseResponse(resp)
+
+	return
+}
+
+func (c *FasthttpClient) DoRequest(r *StepRequest) (
+	resp StepResponse, usTaken uint64, err error,
+) {
+	req := fasthttp.AcquireRequest()
+	fresp := fasthttp.AcquireResponse()
+	defer func() {
+		fasthttp.ReleaseRequest(req)
+		fasthttp.ReleaseResponse(fresp)
+	}()
+	if c.headers != nil {
+		c.headers.CopyTo(&req.Header)
+	}
+	for _, h := range r.headers {
+		req.Header.Set(h.key, h.value)
+	}
+	if len(req.Header.Host()) == 0 {
+		req.Header.SetHost(c.host)
+	}
+	req.Header.SetMethod(r.method)
+	if c.absoluteURI {
+		req.SetRequestURI(r.url.String())
+	} else {
+		req.SetRequestURI(r.url.RequestURI())
+	}
+	req.SetBodyString(r.body)
+	if err = c.prepare(req, r.method, r.url, &r.body); err != nil {
+		return StepResponse{code: -1}, 0, err
+	}
+
+	resp.code, usTaken, err = c.send(req, fresp, r.url)
+	if err == nil {
+		resp.header = FastHTTPResponseHeader(fresp)
+		var body bytes.Buffer
+		err = c.readBody(fresp, &body)
+		resp.body = body.Bytes()
+	}
+	if c.trace != nil {
+		c.traceExchange(req, fresp, r.url, true, resp.code, usTaken, err)
+	}
+	return
+}
+
+// traceExchange passes description of the request and its response
+// to the trace, bodies are counted only if they were read.
+func (c *FasthttpClient) traceExchange(
+	req *fasthttp.Request, resp *fasthttp.Response, u *url.URL,
+	withBody bool, code int, usTaken uint64, err error,
+) {
+	e := newTraceEntry(
+		string(req.Header.Method()), u.String(), code, usTaken, err,
+	)
+	e.BytesOut = int64(len(req.Header.Header()))
+	if withBody {
+		e.BytesOut += int64(len(req.Body()))
+	}
+	if err == nil {
+		e.BytesIn = int64(len(resp.Header.Header()) + len(resp.Body()))
+	}
+	c.trace(e)
+}
+
+// readBody writes body of the response, decoded if needed, to w and
+// samples the response.
+func (c *FasthttpClient) readBody(
+	resp *fasthttp.Response, w io.Writer,
+) (err error) {
+	var captured *capturedResponse
+	if c.sampler != nil {
+		captured, w = c.sampler.capture(resp.StatusCode(), w)
+	}
+	if c.decompressor != nil {
+		err = c.decompress(resp, w)
+	} else {
+		_, err = w.Write(resp.Body())
+	}
+	if err != nil {
+		return &bodyReadError{err}
+	}
+	if captured != nil {
+		c.sampler.keep(captured, FastHTTPResponseHeader(resp))
+	}
+	return nil
+}
+
+// decompress writes decoded body of the response to w.
+func (c *FasthttpClient) decompress(
+	resp *fasthttp.Response, w io.Writer,
+) error {
+	var encoding string
+	// Header names aren't normalized, so they have to be compared
+	// case-insensitively
+	resp.Header.VisitAll(func(key, value []byte) {
+		if strings.EqualFold(string(key), "Content-Encoding") {
+			encoding = string(value)
+		}
+	})
+	return c.decompressor.Decode(
+		encoding, bytes.NewReader(resp.Body()), w,
+	)
+}
+
+// prepare adds authorization headers and cookies to the request.
+func (c *FasthttpClient) prepare(
+	req *fasthttp.Request, method string, u *url.URL, body *string,
+) error {
+	if c.auth != nil {
+		headers, err := c.auth.Authorize(
+			method, string(req.Header.Host()), u, body,
+		)
+		if err != nil {
+			return err
+		}
+		for _, h := range headers {
+			req.Header.Set(h.key, h.value)
+		}
+	}
+	if c.jar != nil {
+		addFastHTTPCookies(c.jar, u, req)
+	}
+	return nil
+}
 
-	// fire the request
+// send fires the request and records its statistics.
+func (c *FasthttpClient) send(
+	req *fasthttp.Request, resp *fasthttp.Response, u *url.URL,
+) (code int, usTaken uint64, err error) {
+	if c.pipeline != nil {
+		c.pipeline.Begin()
+	}
+	if c.lifetime != nil && c.lifetime.Last() {
+		req.SetConnectionClose()
+	}
 	start := time.Now()
 	err = c.client.Do(req, resp)
 	if err != nil {
@@ -106,16 +363,34 @@ func (c *FasthttpClient) Do() (
 		code = resp.StatusCode()
 	}
 	usTaken = uint64(time.Since(start).Nanoseconds() / 1000)
-
-	// release resources
-	fasthttp.ReleaseRequest(req)
-	fasthttp.ReleaseResponse(resp)
-
+	if c.pipeline != nil {
+		c.pipeline.End(err)
+	}
+	// Pipelined responses don't know the addresses of their connections
+	if c.addrs != nil && err == nil && resp.RemoteAddr() != nil {
+		c.addrs.Add(resp.RemoteAddr().String(), usTaken)
+	}
+	if c.identities != nil && err == nil && resp.LocalAddr() != nil {
+		c.identities.Add(resp.LocalAddr())
+	}
+	if c.jar != nil && err == nil {
+		storeFastHTTPCookies(c.jar, u, resp)
+	}
 	return
 }
 
 type HttpClient struct {
-	client *http.Client
+	client       *http.Client
+	transport    *http.Transport
+	conns        *ConnStats
+	lifetime     *ConnLifetime
+	streams      *ConnStreams
+	addrs        *AddrMap
+	identities   *ClientIdentities
+	auth         Authorizer
+	decompressor *Decompressor
+	sampler      *ResponseSampler
+	trace        func(*TraceEntry)
 
 	headers http.Header
 	url     *url.URL
@@ -132,7 +407,12 @@ func NewHTTPClient(opts *ClientOpts) Client {
 		MaxIdleConnsPerHost: int(opts.maxConns),
 		DisableKeepAlives:   opts.disableKeepAlives,
 	}
-	tr.DialContext = HttpDialContextFunc(opts.bytesRead, opts.bytesWritten)
+	if opts.streams != nil {
+		// Otherwise connections are dialed while the first one is being
+		// established and streams are spread over all of them
+		tr.MaxConnsPerHost = int(opts.maxConns)
+	}
+	tr.DialContext = HttpDialContextFunc(opts)
 	if opts.HTTP2 {
 		_ = http2.ConfigureTransport(tr)
 	} else {
@@ -140,6 +420,9 @@ func NewHTTPClient(opts *ClientOpts) Client {
 			map[string]func(authority string, c *tls.Conn) http.RoundTripper,
 		)
 	}
+	// This has to be done after HTTP/2 is configured, since it adds h2
+	// to the list of supported application protocols.
+	tr.DialTLSContext = HttpDialTLSContextFunc(opts, tr.TLSClientConfig)
 
 	cl := &http.Client{
 		Transport: tr,
@@ -147,21 +430,56 @@ func NewHTTPClient(opts *ClientOpts) Client {
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
+		Jar: opts.cookieJar,
 	}
-	c.client = cl
+	c.client, c.transport = cl, tr
+	c.conns, c.lifetime = opts.connStats, opts.lifetime
+	c.streams = opts.streams
 
 	c.headers = HeadersToHTTPHeaders(opts.headers)
 	c.method, c.body, c.bodProd = opts.method, opts.body, opts.bodProd
+	c.addrs, c.identities = opts.addrs, opts.identities
+	c.auth, c.decompressor = opts.auth, opts.decompressor
+	c.sampler = opts.sampler
 	var err error
 	c.url, err = url.Parse(opts.url)
 	if err != nil {
 		// opts.url guaranteed to be valid at this point
 		panic(err)
 	}
+	if opts.proxy != "" {
+		proxy, perr := url.Parse(opts.proxy)
+		if perr != nil {
+			// opts.proxy guaranteed to be valid at this point
+			panic(perr)
+		}
+		// Tunnels are established by the dialer, net/http only has to
+		// know about proxies that forward requests.
+		if ForwardsThroughProxy(proxy, c.url) {
+			tr.Proxy = http.ProxyURL(proxy)
+		}
+	}
 
 	return Client(c)
 }
 
+func (c *HttpClient) WithCookieJar(jar http.CookieJar) Client {
+	cc, cl := *c, *c.client
+	cl.Jar = jar
+	cc.client = &cl
+	return Client(&cc)
+}
+
+func (c *HttpClient) CloseIdleConnections() {
+	c.transport.CloseIdleConnections()
+}
+
+func (c *HttpClient) WithTrace(trace func(*TraceEntry)) Client {
+	cc := *c
+	cc.trace = trace
+	return Client(&cc)
+}
+
 func (c *HttpClient) Do() (
 	code int, usTaken uint64, err error,
 ) {
@@ -175,6 +493,15 @@ func (c *HttpClient) Do() (
 		req.Host = host
 	}
 
+	// Headers are shared by all the requests, so they have to be copied
+	// before authorization headers or cookies are added to them
+	if c.auth != nil || c.client.Jar != nil {
+		req.Header = req.Header.Clone()
+	}
+	if aerr := c.authorize(req, c.body); aerr != nil {
+		return 0, 0, aerr
+	}
+
 	if c.body != nil {
 		br := strings.NewReader(*c.body)
 		req.ContentLength = int64(len(*c.body))
@@ -187,27 +514,165 @@ func (c *HttpClient) Do() (
 		req.Body = bs
 	}
 
+	resp, usTaken, err := c.send(req, ioutil.Discard)
+	return resp.code, usTaken, err
+}
+
+func (c *HttpClient) DoRequest(r *StepRequest) (
+	resp StepResponse, usTaken uint64, err error,
+) {
+	req := &http.Request{
+		Method: r.method,
+		URL:    r.url,
+		Header: c.headers.Clone(),
+	}
+	for _, h := range r.headers {
+		req.Header.Set(h.key, h.value)
+	}
+	if host := req.Header.Get("Host"); host != "" {
+		req.Host = host
+	}
+	if err = c.authorize(req, &r.body); err != nil {
+		return StepResponse{code: -1}, 0, err
+	}
+	req.ContentLength = int64(len(r.body))
+	req.Body = ioutil.NopCloser(strings.NewReader(r.body))
+
+	var body bytes.Buffer
+	resp, usTaken, err = c.send(req, &body)
+	resp.body = body.Bytes()
+	return
+}
+
+// authorize adds authorization headers to the request.
+func (c *HttpClient) authorize(req *http.Request, body *string) error {
+	if c.auth == nil {
+		return nil
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers, err := c.auth.Authorize(req.Method, host, req.URL, body)
+	if err != nil {
+		return err
+	}
+	for _, h := range headers {
+		req.Header.Set(h.key, h.value)
+	}
+	return nil
+}
+
+// send fires the request, copies response body to w and records
+// statistics of the request.
+func (c *HttpClient) send(req *http.Request, w io.Writer) (
+	resp StepResponse, usTaken uint64, err error,
+) {
+	var (
+		remoteAddr string
+		localAddr  net.Addr
+	)
+	if c.addrs != nil || c.identities != nil || c.conns != nil {
+		trace := &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				remoteAddr = info.Conn.RemoteAddr().String()
+				localAddr = info.Conn.LocalAddr()
+				if c.conns != nil && info.Reused {
+					c.conns.Reuse()
+				}
+			},
+		}
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	}
+	last := c.lifetime != nil && c.lifetime.Last()
+	if last {
+		req.Close = true
+	}
+
+	if c.streams != nil {
+		c.streams.Begin()
+	}
+	var received *countingReader
 	start := time.Now()
-	resp, err := c.client.Do(req)
+	hresp, err := c.client.Do(req)
 	if err != nil {
-		code = -1
+		resp.code = -1
 	} else {
-		code = resp.StatusCode
+		resp.code, resp.header = hresp.StatusCode, hresp.Header
 
-		_, berr := io.Copy(ioutil.Discard, resp.Body)
+		var captured *capturedResponse
+		if c.sampler != nil {
+			captured, w = c.sampler.capture(hresp.StatusCode, w)
+		}
+		var body io.Reader = hresp.Body
+		if c.trace != nil {
+			received = &countingReader{r: body}
+			body = received
+		}
+		var berr error
+		if c.decompressor != nil {
+			berr = c.decompressor.Decode(
+				hresp.Header.Get("Content-Encoding"), body, w,
+			)
+		} else {
+			_, berr = io.Copy(w, body)
+		}
 		if berr != nil {
-			err = berr
+			err = &bodyReadError{berr}
 		}
 
-		if cerr := resp.Body.Close(); cerr != nil {
+		if cerr := hresp.Body.Close(); cerr != nil {
 			err = cerr
 		}
+		if err == nil && captured != nil {
+			c.sampler.keep(captured, hresp.Header)
+		}
 	}
 	usTaken = uint64(time.Since(start).Nanoseconds() / 1000)
+	if c.streams != nil {
+		c.streams.End()
+	}
+	if last {
+		// HTTP/2 connections stay open even if the request asked to
+		// close them
+		c.transport.CloseIdleConnections()
+	}
+	if c.addrs != nil && err == nil && remoteAddr != "" {
+		c.addrs.Add(remoteAddr, usTaken)
+	}
+	if c.identities != nil && err == nil && localAddr != nil {
+		c.identities.Add(localAddr)
+	}
+	if c.trace != nil {
+		c.traceExchange(req, hresp, received, resp.code, usTaken, err)
+	}
 
 	return
 }
 
+// traceExchange passes description of the request and its response
+// to the trace. Sizes of the headers are estimated, since net/http
+// doesn't report them.
+func (c *HttpClient) traceExchange(
+	req *http.Request, hresp *http.Response, received *countingReader,
+	code int, usTaken uint64, err error,
+) {
+	e := newTraceEntry(req.Method, req.URL.String(), code, usTaken, err)
+	requestLine := req.Method + " " + req.URL.RequestURI() + " HTTP/1.1\r\n"
+	e.BytesOut = int64(len(requestLine)) + headerSize(req.Header)
+	if req.ContentLength > 0 {
+		e.BytesOut += req.ContentLength
+	}
+	if hresp != nil {
+		statusLine := hresp.Proto + " " + hresp.Status + "\r\n"
+		e.BytesIn = int64(len(statusLine)) + headerSize(hresp.Header)
+	}
+	if received != nil {
+		e.BytesIn += int64(received.n)
+	}
+	c.trace(e)
+}
+
 func HeadersToFastHTTPHeaders(h *HeadersList) *fasthttp.RequestHeader {
 	if len(*h) == 0 {
 		return nil
@@ -219,6 +684,15 @@ func HeadersToFastHTTPHeaders(h *HeadersList) *fasthttp.RequestHeader {
 	return res
 }
 
+// FastHTTPResponseHeader returns headers of the response.
+func FastHTTPResponseHeader(resp *fasthttp.Response) http.Header {
+	header := http.Header{}
+	resp.Header.VisitAll(func(key, value []byte) {
+		header.Add(string(key), string(value))
+	})
+	return header
+}
+
 func HeadersToHTTPHeaders(h *HeadersList) http.Header {
 	if len(*h) == 0 {
 		return http.Header{}
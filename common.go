@@ -49,11 +49,90 @@ var (
 		"No Path to TLS Client Certificate Private Key")
 	errZeroRate = errors.New(
 		"Rate can't be less than 1")
-	errBodyProvidedTwice = errors.New("Use either --body or --body-file")
+	errBodyProvidedTwice       = errors.New("Use either --body or --body-file")
+	errUnixSocketProvidedTwice = errors.New(
+		"Use either --unix-socket or http+unix:// URL")
 
 	errInvalidHeaderFormat = errors.New("Invalid Header Format")
 	errEmptyPrintSpec      = errors.New(
 		"Empty print spec is not a valid print spec")
+
+	errInvalidConnectToFormat = errors.New(
+		"Invalid connect-to format, expected HOST1:PORT1:HOST2:PORT2")
+	errInvalidResolveFormat = errors.New(
+		"Invalid resolve format, expected HOST:PORT:ADDR[,ADDR]...")
+	errLocalAddrVersionMismatch = errors.New(
+		"Local addresses must match IP version selected with -4/-6")
+	errInvalidProxy = errors.New(
+		"No proxy hostname or invalid proxy scheme(must be http or socks5)")
+	errProxyWithUnixSocket = errors.New(
+		"Proxy can't be used with Unix domain sockets")
+	errTLSMinGreaterThanMax = errors.New(
+		"Minimum TLS version can't be greater than maximum")
+	errCertKeyCountMismatch = errors.New(
+		"Number of certificates and keys doesn't match")
+	errInvalidTLSResumption = errors.New(
+		"Unknown TLS resumption mode(must be off, tickets or cache)")
+	errMultipleAuthModes = errors.New(
+		"Use only one of --basic, --bearer-file, --hmac-sha256, " +
+			"--aws-sigv4 or --oauth2-token-url")
+	errInvalidBasicAuth = errors.New(
+		"Invalid basic auth format, expected user:pass")
+	errInvalidHMACFormat = errors.New(
+		"Invalid HMAC format, expected key,header")
+	errInvalidAWSSigV4Format = errors.New(
+		"Invalid AWS SigV4 format, expected region/service")
+	errNoAWSCredentials = errors.New(
+		"AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set " +
+			"to sign requests")
+	errSigningStreamedBody = errors.New(
+		"Streamed bodies can't be signed")
+	errInvalidOAuth2TokenURL = errors.New(
+		"No hostname or invalid scheme in OAuth2 token URL")
+	errNoOAuth2ClientID = errors.New(
+		"Client ID is required to obtain OAuth2 token")
+	errInvalidCookiesMode = errors.New(
+		"Unknown cookies mode(must be shared or worker)")
+	errEmptyScenario = errors.New(
+		"Scenario must contain at least one step")
+	errNoExtractorVariable = errors.New(
+		"Variable to store extracted value in is required")
+	errInvalidExtractor = errors.New(
+		"Extractor must use exactly one of json, regex or header")
+	errScenarioOtherHost = errors.New(
+		"Scenario steps must target the host being tested")
+	errScenarioWithStream = errors.New(
+		"Scenario bodies can't be streamed")
+	errInvalidThinkTime = errors.New(
+		"Invalid think time, expected DURATION, uniform:MIN,MAX, " +
+			"normal:MEAN,STDDEV or exponential:MEAN")
+	errNegativePacing = errors.New(
+		"Pacing can't be negative")
+	errInvalidFormFormat = errors.New(
+		"Invalid form field format, expected key=value or key=@path")
+	errFormWithBody = errors.New(
+		"Use either --form or --body/--body-file")
+	errInvalidEncoding = errors.New(
+		"Unknown body encoding(must be gzip, deflate, br or zstd)")
+	errPipelineRequiresFastHTTP = errors.New(
+		"Pipelining is only supported by fasthttp client")
+	errNegativeConnAge = errors.New(
+		"Maximum connection age can't be negative")
+	errConnLifetimeWithPipeline = errors.New(
+		"Connection lifetime can't be limited when pipelining")
+	errStreamsRequireHTTP2 = errors.New(
+		"Streams per connection can only be set for net/http v2.0 client")
+	errStreamsRequireHTTPS = errors.New(
+		"Streams per connection can only be set for https:// targets")
+	errConnLifetimeWithStreams = errors.New(
+		"Connection lifetime can't be limited when multiplexing streams")
+	errInvalidBandwidthLimit = errors.New(
+		"Invalid bandwidth limit, expected RATE or UP,DOWN " +
+			"(e.g. 256KB or 1mbps,8mbps)")
+	errNegativeConnLatency = errors.New(
+		"Connection latency can't be negative")
+	errSampleDirWithoutSampling = errors.New(
+		"Samples directory requires --sample-responses")
 )
 
 func init() {
@@ -0,0 +1,148 @@
+package bombardier
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync/atomic"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	encodingGzip     = "gzip"
+	encodingDeflate  = "deflate"
+	encodingBrotli   = "br"
+	encodingZstd     = "zstd"
+	encodingIdentity = "identity"
+)
+
+// CompressBody encodes body using the given content coding.
+func CompressBody(encoding, body string) (string, error) {
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+		err error
+	)
+	switch encoding {
+	case encodingGzip:
+		w = gzip.NewWriter(&buf)
+	case encodingDeflate:
+		w = zlib.NewWriter(&buf)
+	case encodingBrotli:
+		w = brotli.NewWriter(&buf)
+	case encodingZstd:
+		if w, err = zstd.NewWriter(&buf); err != nil {
+			return "", err
+		}
+	default:
+		return "", errInvalidEncoding
+	}
+	if _, err := io.WriteString(w, body); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Decompressor decodes response bodies and keeps track of how many
+// bytes were received compressed, what they were decoded to and how
+// many bodies failed to decode.
+type Decompressor struct {
+	compressed, decompressed uint64
+	errors                   uint64
+}
+
+func NewDecompressor() *Decompressor {
+	return new(Decompressor)
+}
+
+// Decode writes body decoded according to encoding (value of the
+// Content-Encoding header) to w.
+func (d *Decompressor) Decode(
+	encoding string, body io.Reader, w io.Writer,
+) error {
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+	if encoding == "" || encoding == encodingIdentity {
+		_, err := io.Copy(w, body)
+		return err
+	}
+	cr := &countingReader{r: body}
+	n, err := d.decode(encoding, cr, w)
+	atomic.AddUint64(&d.compressed, cr.n)
+	atomic.AddUint64(&d.decompressed, uint64(n))
+	if err != nil {
+		atomic.AddUint64(&d.errors, 1)
+		return fmt.Errorf("decompression failed: %v", err)
+	}
+	return nil
+}
+
+func (d *Decompressor) decode(
+	encoding string, body io.Reader, w io.Writer,
+) (int64, error) {
+	var (
+		r   io.ReadCloser
+		err error
+	)
+	switch encoding {
+	case encodingGzip, "x-gzip":
+		r, err = gzip.NewReader(body)
+	case encodingDeflate:
+		r, err = zlib.NewReader(body)
+	case encodingBrotli:
+		r = ioutil.NopCloser(brotli.NewReader(body))
+	case encodingZstd:
+		// Decoders are used for a single body, so they don't need
+		// goroutines of their own
+		var zr *zstd.Decoder
+		zr, err = zstd.NewReader(body, zstd.WithDecoderConcurrency(1))
+		if err == nil {
+			r = zr.IOReadCloser()
+		}
+	default:
+		return 0, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(w, r)
+	if cerr := r.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+// Compressed returns number of compressed bytes received.
+func (d *Decompressor) Compressed() uint64 {
+	return atomic.LoadUint64(&d.compressed)
+}
+
+// Decompressed returns number of bytes compressed bodies were decoded
+// to.
+func (d *Decompressor) Decompressed() uint64 {
+	return atomic.LoadUint64(&d.decompressed)
+}
+
+// Errors returns number of bodies that failed to decode.
+func (d *Decompressor) Errors() uint64 {
+	return atomic.LoadUint64(&d.errors)
+}
+
+type countingReader struct {
+	r io.Reader
+	n uint64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += uint64(n)
+	return n, err
+}
@@ -0,0 +1,102 @@
+package bombardier
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestCompressBody(t *testing.T) {
+	body := strings.Repeat("bombardier ", 100)
+	for _, encoding := range []string{
+		encodingGzip, encodingDeflate, encodingBrotli, encodingZstd,
+	} {
+		compressed, err := CompressBody(encoding, body)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if len(compressed) >= len(body) {
+			t.Errorf("%v: body wasn't compressed", encoding)
+		}
+		var decoded bytes.Buffer
+		d := NewDecompressor()
+		if err = d.Decode(
+			encoding, strings.NewReader(compressed), &decoded,
+		); err != nil {
+			t.Error(err)
+			continue
+		}
+		if decoded.String() != body {
+			t.Errorf("%v: body didn't survive compression", encoding)
+		}
+		if d.Compressed() != uint64(len(compressed)) ||
+			d.Decompressed() != uint64(len(body)) || d.Errors() != 0 {
+			t.Errorf("%v: unexpected stats %v, %v, %v", encoding,
+				d.Compressed(), d.Decompressed(), d.Errors())
+		}
+	}
+	expectations := []struct {
+		in  string
+		err error
+	}{
+		{"lzma", errInvalidEncoding},
+	}
+	for _, e := range expectations {
+		if _, err := CompressBody(e.in, body); err != e.err {
+			t.Errorf("%v: expected %v, but got %v", e.in, e.err, err)
+		}
+	}
+}
+
+func TestDecompressorDecode(t *testing.T) {
+	var gzipped, deflated bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	_, _ = gw.Write([]byte("hello"))
+	_ = gw.Close()
+	zw := zlib.NewWriter(&deflated)
+	_, _ = zw.Write([]byte("hello"))
+	_ = zw.Close()
+	brotlied, _ := CompressBody(encodingBrotli, "hello")
+	zstded, _ := CompressBody(encodingZstd, "hello")
+	expectations := []struct {
+		encoding, body, out string
+		failed              bool
+	}{
+		{"", "hello", "hello", false},
+		{"identity", "hello", "hello", false},
+		{"GZIP", gzipped.String(), "hello", false},
+		{"x-gzip", gzipped.String(), "hello", false},
+		{"deflate", deflated.String(), "hello", false},
+		{"gzip", "hello", "", true},
+		{"gzip", gzipped.String()[:gzipped.Len()-4], "hello", true},
+		{"br", brotlied, "hello", false},
+		{"zstd", zstded, "hello", false},
+		{"zstd", "hello", "", true},
+		{"lzma", "hello", "", true},
+	}
+	for _, e := range expectations {
+		d := NewDecompressor()
+		var out bytes.Buffer
+		err := d.Decode(e.encoding, strings.NewReader(e.body), &out)
+		if (err != nil) != e.failed {
+			t.Errorf("%v(%q): unexpected error %v", e.encoding, e.body, err)
+		}
+		if e.failed && d.Errors() != 1 {
+			t.Errorf("%v: expected error to be counted", e.encoding)
+		}
+		if out.String() != e.out {
+			t.Errorf("%v: expected %q, but got %q", e.encoding, e.out,
+				out.String())
+		}
+	}
+	// Uncompressed bodies are not counted
+	d := NewDecompressor()
+	_ = d.Decode("", strings.NewReader("hello"), ioutil.Discard)
+	if d.Compressed() != 0 || d.Decompressed() != 0 {
+		t.Error("uncompressed bodies shouldn't be counted")
+	}
+}
@@ -2,8 +2,11 @@ package bombardier
 
 import (
 	"fmt"
+	"net"
 	"net/url"
+	"os"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -13,7 +16,40 @@ type Config struct {
 	disableKeepAlives              bool
 	duration                       *time.Duration
 	url, method, certPath, keyPath string
+	unixSocket                     string
+	connectTo                      *ConnectToList
+	resolve                        *ResolveList
+	spreadAddrs                    bool
+	localAddrs                     *LocalAddrList
+	ipVersion                      int
+	proxy                          string
+	caCertPath, sni                string
+	tlsMinVersion, tlsMaxVersion   string
+	ciphers, curves, alpn          string
+	tlsResumption, tlsKeyLog       string
+	basicAuth, bearerFile          string
+	hmacSHA256, awsSigV4           string
+	oauth2TokenURL, scope          string
+	clientID, clientSecret         string
+	cookies                        string
+	scenarioPath                   string
+	thinkTime                      *ThinkTime
+	pacing                         time.Duration
 	body, bodyFilePath             string
+	form                           *FormFields
+	compressBody, acceptEncoding   string
+	decompress                     bool
+	pipeline                       uint64
+	maxConnRequests                uint64
+	maxConnAge                     time.Duration
+	streamsPerConn                 uint64
+	bandwidth                      *BandwidthLimit
+	connLatency                    time.Duration
+	sampleResponses                uint64
+	sampleDir                      string
+	traceLog                       string
+	errorHistogram                 bool
+	statusLatencies                bool
 	stream                         bool
 	headers                        *HeadersList
 	timeout                        time.Duration
@@ -54,8 +90,21 @@ func (c *Config) CheckArgs() error {
 		c.CheckRate,
 		c.CheckRunParameters,
 		c.CheckTimeoutDuration,
+		c.CheckPacing,
 		c.CheckHTTPParameters,
+		c.CheckCompression,
+		c.CheckPipeline,
+		c.CheckConnLifetime,
+		c.CheckStreams,
+		c.CheckConnLatency,
+		c.CheckSampling,
 		c.CheckCertPaths,
+		c.CheckTLSParameters,
+		c.CheckLocalAddrs,
+		c.CheckProxy,
+		c.CheckAuth,
+		c.CheckCookies,
+		c.CheckScenario,
 	}
 
 	for _, check := range checks {
@@ -122,21 +171,119 @@ func (c *Config) CheckTimeoutDuration() error {
 	return nil
 }
 
+func (c *Config) CheckPacing() error {
+	if c.pacing < 0 {
+		return errNegativePacing
+	}
+	return nil
+}
+
 func (c *Config) CheckHTTPParameters() error {
 	if !AllowedHTTPMethod(c.method) {
 		return &InvalidHTTPMethodError{method: c.method}
 	}
-	if !CanHaveBody(c.method) && (c.body != "" || c.bodyFilePath != "") {
+	if !CanHaveBody(c.method) &&
+		(c.body != "" || c.bodyFilePath != "" || c.form != nil) {
 		return errBodyNotAllowed
 	}
 	if c.body != "" && c.bodyFilePath != "" {
 		return errBodyProvidedTwice
 	}
+	if c.form != nil && (c.body != "" || c.bodyFilePath != "") {
+		return errFormWithBody
+	}
+	return nil
+}
+
+func (c *Config) CheckCompression() error {
+	switch c.compressBody {
+	case "", encodingGzip, encodingDeflate, encodingBrotli, encodingZstd:
+		return nil
+	}
+	return errInvalidEncoding
+}
+
+func (c *Config) CheckPipeline() error {
+	if c.pipeline > 0 && c.clientType != fhttp {
+		return errPipelineRequiresFastHTTP
+	}
 	return nil
 }
 
+func (c *Config) CheckConnLifetime() error {
+	if c.maxConnAge < 0 {
+		return errNegativeConnAge
+	}
+	if c.pipeline > 0 && c.LimitsConnLifetime() {
+		return errConnLifetimeWithPipeline
+	}
+	return nil
+}
+
+func (c *Config) CheckStreams() error {
+	if c.streamsPerConn == 0 {
+		return nil
+	}
+	if c.clientType != nhttp2 {
+		return errStreamsRequireHTTP2
+	}
+	// HTTP/2 is only negotiated over TLS, plain HTTP targets would get
+	// a single request per connection at a time
+	if u, err := url.Parse(c.url); err != nil || u.Scheme != "https" {
+		return errStreamsRequireHTTPS
+	}
+	if c.LimitsConnLifetime() {
+		return errConnLifetimeWithStreams
+	}
+	return nil
+}
+
+func (c *Config) CheckConnLatency() error {
+	if c.connLatency < 0 {
+		return errNegativeConnLatency
+	}
+	return nil
+}
+
+func (c *Config) CheckSampling() error {
+	if c.sampleDir != "" && c.sampleResponses == 0 {
+		return errSampleDirWithoutSampling
+	}
+	return nil
+}
+
+// LimitsConnLifetime tells if connections have to be closed after a
+// number of requests or once they get too old. Each worker owns a
+// single connection then.
+func (c *Config) LimitsConnLifetime() bool {
+	return c.maxConnRequests > 0 || c.maxConnAge > 0
+}
+
+// Workers returns number of goroutines sending requests. Pipelined
+// connections need a worker for each of the pending requests, as well
+// as HTTP/2 connections need one for each of the streams.
+func (c *Config) Workers() uint64 {
+	if c.pipeline > 0 {
+		return c.numConns * c.pipeline
+	}
+	if c.streamsPerConn > 0 {
+		return c.numConns * c.streamsPerConn
+	}
+	return c.numConns
+}
+
+// PinsConnections tells if workers are pinned to the connections, so
+// that each connection is owned by a client of its own.
+func (c *Config) PinsConnections() bool {
+	return c.streamsPerConn > 0 || c.LimitsConnLifetime()
+}
+
 func (c *Config) CheckCertPaths() error {
 	if c.certPath != "" && c.keyPath == "" {
+		// Keys are looked up alongside with certificates then
+		if fi, err := os.Stat(c.certPath); err == nil && fi.IsDir() {
+			return nil
+		}
 		return errNoPathToKey
 	} else if c.certPath == "" && c.keyPath != "" {
 		return errNoPathToCert
@@ -144,6 +291,109 @@ func (c *Config) CheckCertPaths() error {
 	return nil
 }
 
+func (c *Config) CheckLocalAddrs() error {
+	if c.localAddrs == nil || c.ipVersion == anyIPVersion {
+		return nil
+	}
+	for _, addr := range *c.localAddrs {
+		if IPVersionOf(net.ParseIP(addr)) != c.ipVersion {
+			return errLocalAddrVersionMismatch
+		}
+	}
+	return nil
+}
+
+func (c *Config) CheckProxy() error {
+	if c.proxy == "" {
+		return nil
+	}
+	if c.unixSocket != "" {
+		return errProxyWithUnixSocket
+	}
+	proxy, err := url.Parse(c.proxy)
+	if err != nil {
+		return err
+	}
+	if proxy.Hostname() == "" ||
+		(proxy.Scheme != "http" && proxy.Scheme != "socks5") {
+		return errInvalidProxy
+	}
+	return nil
+}
+
+func (c *Config) CheckTLSParameters() error {
+	minVersion, err := ParseTLSVersion(c.tlsMinVersion)
+	if err != nil {
+		return err
+	}
+	maxVersion, err := ParseTLSVersion(c.tlsMaxVersion)
+	if err != nil {
+		return err
+	}
+	if minVersion != 0 && maxVersion != 0 && minVersion > maxVersion {
+		return errTLSMinGreaterThanMax
+	}
+	if _, err = ParseCipherSuites(c.ciphers); err != nil {
+		return err
+	}
+	if _, err = ParseCurves(c.curves); err != nil {
+		return err
+	}
+	switch c.tlsResumption {
+	case "", tlsResumptionOff, tlsResumptionTickets, tlsResumptionCache:
+	default:
+		return errInvalidTLSResumption
+	}
+	return nil
+}
+
+func (c *Config) CheckAuth() error {
+	modes := 0
+	for _, m := range []string{
+		c.basicAuth, c.bearerFile, c.hmacSHA256, c.awsSigV4,
+		c.oauth2TokenURL,
+	} {
+		if m != "" {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return errMultipleAuthModes
+	}
+	if c.basicAuth != "" && !strings.Contains(c.basicAuth, ":") {
+		return errInvalidBasicAuth
+	}
+	if (c.hmacSHA256 != "" || c.awsSigV4 != "") && c.stream {
+		return errSigningStreamedBody
+	}
+	if c.oauth2TokenURL != "" {
+		u, err := url.Parse(c.oauth2TokenURL)
+		if err != nil || u.Host == "" ||
+			(u.Scheme != "http" && u.Scheme != "https") {
+			return errInvalidOAuth2TokenURL
+		}
+		if c.clientID == "" {
+			return errNoOAuth2ClientID
+		}
+	}
+	return nil
+}
+
+func (c *Config) CheckCookies() error {
+	switch c.cookies {
+	case "", cookiesShared, cookiesPerWorker:
+		return nil
+	}
+	return errInvalidCookiesMode
+}
+
+func (c *Config) CheckScenario() error {
+	if c.scenarioPath != "" && c.stream {
+		return errScenarioWithStream
+	}
+	return nil
+}
+
 func (c *Config) TimeoutMillis() uint64 {
 	return uint64(c.timeout.Nanoseconds() / 1000)
 }
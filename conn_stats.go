@@ -0,0 +1,152 @@
+package bombardier
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ConnStats keeps track of connections opened by the clients, of
+// connections that were closed by the server and of requests that were
+// sent over already used connections.
+type ConnStats struct {
+	opened, closedByServer, reused uint64
+}
+
+func NewConnStats() *ConnStats {
+	return new(ConnStats)
+}
+
+// Reuse records request sent over a connection that was used before.
+func (s *ConnStats) Reuse() {
+	atomic.AddUint64(&s.reused, 1)
+}
+
+// Dial wraps dial, so that requests sent over connections established
+// by fasthttp, which has no hooks of its own, are counted.
+func (s *ConnStats) Dial(
+	dial func(string) (net.Conn, error),
+) func(string) (net.Conn, error) {
+	return func(address string) (net.Conn, error) {
+		conn, err := dial(address)
+		if err != nil {
+			return nil, err
+		}
+		return &requestCountingConn{Conn: conn, stats: s}, nil
+	}
+}
+
+// Opened returns number of connections established.
+func (s *ConnStats) Opened() uint64 {
+	return atomic.LoadUint64(&s.opened)
+}
+
+// ClosedByServer returns number of connections closed by the server.
+func (s *ConnStats) ClosedByServer() uint64 {
+	return atomic.LoadUint64(&s.closedByServer)
+}
+
+// Reused returns number of requests sent over already used
+// connections.
+func (s *ConnStats) Reused() uint64 {
+	return atomic.LoadUint64(&s.reused)
+}
+
+// closedByPeer tells if err returned by Read means that the other side
+// has closed the connection.
+func closedByPeer(err error) bool {
+	if err == io.EOF {
+		return true
+	}
+	var errno syscall.Errno
+	return errors.As(err, &errno) && errno == syscall.ECONNRESET
+}
+
+// requestCountingConn assumes that a request starts with the first
+// write following a read, which holds for HTTP/1.x connections that
+// aren't pipelined.
+type requestCountingConn struct {
+	net.Conn
+	stats    *ConnStats
+	reading  int32
+	requests uint64
+}
+
+func (r *requestCountingConn) Read(b []byte) (int, error) {
+	n, err := r.Conn.Read(b)
+	if n > 0 {
+		atomic.StoreInt32(&r.reading, 1)
+	}
+	return n, err
+}
+
+func (r *requestCountingConn) Write(b []byte) (int, error) {
+	if atomic.SwapInt32(&r.reading, 0) == 1 ||
+		atomic.LoadUint64(&r.requests) == 0 {
+		if atomic.AddUint64(&r.requests, 1) > 1 {
+			r.stats.Reuse()
+		}
+	}
+	return r.Conn.Write(b)
+}
+
+// ConnLifetime limits number of requests sent over and age of the only
+// connection of a worker's client. Each request asks whether it has to
+// be the last one on its connection, so that it can ask the server to
+// close the connection and the next one is sent over a new connection.
+type ConnLifetime struct {
+	maxRequests uint64
+	maxAge      time.Duration
+
+	mu       sync.Mutex
+	conn     net.Conn
+	opened   time.Time
+	requests uint64
+}
+
+func NewConnLifetime(
+	maxRequests uint64, maxAge time.Duration,
+) *ConnLifetime {
+	return &ConnLifetime{maxRequests: maxRequests, maxAge: maxAge}
+}
+
+// Opened records that conn was established. Connections are dialed
+// while the request that needed them is already counted, so that
+// request is the first one sent over conn.
+func (l *ConnLifetime) Opened(conn net.Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.conn, l.opened = conn, time.Now()
+	if l.requests == 0 {
+		l.requests = 1
+	}
+}
+
+// Closed records that conn was closed by either side.
+func (l *ConnLifetime) Closed(conn net.Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn == conn {
+		l.conn, l.requests = nil, 0
+	}
+}
+
+// Last counts a request about to be sent and tells whether the
+// connection has to be closed after it.
+func (l *ConnLifetime) Last() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests++
+	last := l.maxRequests > 0 && l.requests >= l.maxRequests
+	if l.maxAge > 0 && l.conn != nil && time.Since(l.opened) >= l.maxAge {
+		last = true
+	}
+	if last {
+		l.conn, l.requests = nil, 0
+	}
+	return last
+}
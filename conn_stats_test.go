@@ -0,0 +1,182 @@
+package bombardier
+
+import (
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestConnLifetimeMaxRequests(t *testing.T) {
+	l := NewConnLifetime(3, 0)
+	conn := &CountingConn{}
+	expectations := []bool{false, false, true, false, false, true}
+	for i, exp := range expectations {
+		last := l.Last()
+		if i%3 == 0 {
+			// The first request on each connection dials it
+			l.Opened(conn)
+		}
+		if last != exp {
+			t.Errorf("request %v: expected %v, but got %v", i, exp, last)
+		}
+	}
+}
+
+func TestConnLifetimeSingleRequest(t *testing.T) {
+	l := NewConnLifetime(1, 0)
+	for i := 0; i < 3; i++ {
+		if !l.Last() {
+			t.Errorf("request %v: expected to be the last one", i)
+		}
+		l.Opened(&CountingConn{})
+	}
+}
+
+func TestConnLifetimeMaxAge(t *testing.T) {
+	l := NewConnLifetime(0, 10*time.Millisecond)
+	if l.Last() {
+		t.Error("request before connection was opened can't be the last")
+	}
+	l.Opened(&CountingConn{})
+	if l.Last() {
+		t.Error("new connection isn't expected to expire")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if !l.Last() {
+		t.Error("old connection is expected to expire")
+	}
+}
+
+func TestConnLifetimeClosed(t *testing.T) {
+	l := NewConnLifetime(3, 0)
+	first, second := &CountingConn{}, &CountingConn{}
+	l.Last()
+	l.Opened(first)
+	l.Last()
+	// Server closed the connection, so the next request reconnects
+	l.Closed(first)
+	l.Last()
+	l.Opened(second)
+	// Closing connections other than the current one changes nothing
+	l.Closed(first)
+	if l.Last() {
+		t.Error("second request on a connection isn't the last one")
+	}
+	if !l.Last() {
+		t.Error("third request on a connection is the last one")
+	}
+}
+
+func TestClosedByPeer(t *testing.T) {
+	expectations := []struct {
+		in  error
+		out bool
+	}{
+		{io.EOF, true},
+		{syscall.ECONNRESET, true},
+		{&net.OpError{Op: "read", Err: os.NewSyscallError(
+			"read", syscall.ECONNRESET,
+		)}, true},
+		{io.ErrUnexpectedEOF, false},
+		{syscall.ECONNREFUSED, false},
+	}
+	for _, e := range expectations {
+		if out := closedByPeer(e.in); out != e.out {
+			t.Errorf("%v: expected %v, but got %v", e.in, e.out, out)
+		}
+	}
+}
+
+func TestCountingConnClosedByServer(t *testing.T) {
+	client, server := net.Pipe()
+	stats := NewConnStats()
+	lifetime := NewConnLifetime(10, 0)
+	var read, written int64
+	conn := &CountingConn{
+		Conn:         client,
+		bytesRead:    &read,
+		bytesWritten: &written,
+		stats:        stats,
+		lifetime:     lifetime,
+	}
+	lifetime.Last()
+	lifetime.Opened(conn)
+	server.Close()
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected EOF, but got %v", err)
+	}
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected EOF, but got %v", err)
+	}
+	conn.Close()
+	if c := stats.ClosedByServer(); c != 1 {
+		t.Errorf("expected 1 connection closed by server, but got %v", c)
+	}
+	if lifetime.conn != nil || lifetime.requests != 0 {
+		t.Error("expected lifetime of the connection to be over")
+	}
+}
+
+func TestCountingConnClosedLocally(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	stats := NewConnStats()
+	var read, written int64
+	conn := &CountingConn{
+		Conn:         client,
+		bytesRead:    &read,
+		bytesWritten: &written,
+		stats:        stats,
+	}
+	conn.Close()
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Fatal("expected read from closed connection to fail")
+	}
+	if c := stats.ClosedByServer(); c != 0 {
+		t.Errorf("expected no connections closed by server, but got %v", c)
+	}
+}
+
+func TestRequestCountingConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := server.Read(buf)
+			if err != nil {
+				return
+			}
+			// Respond once the empty line ending request was received
+			if string(buf[:n]) == "\r\n" {
+				_, _ = server.Write([]byte("ok"))
+			}
+		}
+	}()
+	stats := NewConnStats()
+	dial := stats.Dial(func(string) (net.Conn, error) {
+		return client, nil
+	})
+	conn, err := dial("example.com:80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for i := 0; i < 3; i++ {
+		// Requests may be written in several chunks
+		for _, chunk := range []string{"GET / HTTP/1.1\r\n", "\r\n"} {
+			if _, err = io.WriteString(conn, chunk); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err = io.ReadFull(conn, make([]byte, 2)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r := stats.Reused(); r != 2 {
+		t.Errorf("expected 2 reused connections, but got %v", r)
+	}
+}
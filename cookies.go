@@ -0,0 +1,60 @@
+package bombardier
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	cookiesShared    = "shared"
+	cookiesPerWorker = "worker"
+)
+
+// NewCookieJar returns empty in-memory cookie jar.
+func NewCookieJar() http.CookieJar {
+	// cookiejar.New never fails without options
+	jar, _ := cookiejar.New(nil)
+	return jar
+}
+
+// CookieJarClient is implemented by clients, that are able to keep
+// cookies set by the server.
+type CookieJarClient interface {
+	// WithCookieJar returns client sharing connections with the
+	// original one, but keeping its cookies in jar.
+	WithCookieJar(jar http.CookieJar) Client
+}
+
+// WithCookieJar returns copy of c that uses jar or c itself if it
+// doesn't support cookies.
+func WithCookieJar(c Client, jar http.CookieJar) Client {
+	if cjc, ok := c.(CookieJarClient); ok {
+		return cjc.WithCookieJar(jar)
+	}
+	return c
+}
+
+func addFastHTTPCookies(
+	jar http.CookieJar, u *url.URL, req *fasthttp.Request,
+) {
+	for _, cookie := range jar.Cookies(u) {
+		req.Header.SetCookie(cookie.Name, cookie.Value)
+	}
+}
+
+func storeFastHTTPCookies(
+	jar http.CookieJar, u *url.URL, resp *fasthttp.Response,
+) {
+	header := http.Header{}
+	resp.Header.VisitAllCookie(func(key, value []byte) {
+		header.Add("Set-Cookie", string(value))
+	})
+	if len(header) == 0 {
+		return
+	}
+	cookies := (&http.Response{Header: header}).Cookies()
+	jar.SetCookies(u, cookies)
+}
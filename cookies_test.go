@@ -0,0 +1,61 @@
+package bombardier
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestFastHTTPCookies(t *testing.T) {
+	jar := NewCookieJar()
+	u, _ := url.Parse("http://localhost:8080/path")
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	for _, kv := range [][2]string{{"session", "abc"}, {"theme", "dark"}} {
+		c := fasthttp.AcquireCookie()
+		c.SetKey(kv[0])
+		c.SetValue(kv[1])
+		resp.Header.SetCookie(c)
+		fasthttp.ReleaseCookie(c)
+	}
+	storeFastHTTPCookies(jar, u, resp)
+	if cookies := jar.Cookies(u); len(cookies) != 2 {
+		t.Fatalf("expected 2 cookies, but got %v", cookies)
+	}
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	addFastHTTPCookies(jar, u, req)
+	if v := string(req.Header.Cookie("session")); v != "abc" {
+		t.Errorf("expected session cookie to be abc, but got %q", v)
+	}
+	if v := string(req.Header.Cookie("theme")); v != "dark" {
+		t.Errorf("expected theme cookie to be dark, but got %q", v)
+	}
+}
+
+func TestWithCookieJar(t *testing.T) {
+	cc := &ClientOpts{
+		headers: new(HeadersList),
+		url:     "http://localhost:8080",
+		method:  "GET",
+		body:    new(string),
+	}
+	for _, c := range []Client{NewHTTPClient(cc), NewFastHTTPClient(cc)} {
+		jar := NewCookieJar()
+		wc := WithCookieJar(c, jar)
+		if wc == c {
+			t.Errorf("%T: expected a copy of the client", c)
+		}
+		switch wc := wc.(type) {
+		case *HttpClient:
+			if wc.client.Jar != jar || c.(*HttpClient).client.Jar != nil {
+				t.Error("jar was set on the wrong client")
+			}
+		case *FasthttpClient:
+			if wc.jar != jar || c.(*FasthttpClient).jar != nil {
+				t.Error("jar was set on the wrong client")
+			}
+		}
+	}
+}
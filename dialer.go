@@ -2,26 +2,61 @@ package bombardier
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
 	"net"
+	"net/url"
+	"strings"
 	"sync/atomic"
+	"time"
 )
 
 type CountingConn struct {
 	net.Conn
 	bytesRead, bytesWritten *int64
+
+	stats    *ConnStats
+	lifetime *ConnLifetime
+	closed   int32
+
+	// Emulate slow network, if set
+	up, down *link
 }
 
 func (cc *CountingConn) Read(b []byte) (n int, err error) {
+	if cc.down != nil {
+		cc.down.delay()
+		b = b[:cc.down.chunk(len(b))]
+	}
 	n, err = cc.Conn.Read(b)
+	if cc.down != nil {
+		cc.down.pass(n)
+	}
 
 	if err == nil {
 		atomic.AddInt64(cc.bytesRead, int64(n))
+	} else if closedByPeer(err) && cc.markClosed() && cc.stats != nil {
+		atomic.AddUint64(&cc.stats.closedByServer, 1)
 	}
 
 	return
 }
 
 func (cc *CountingConn) Write(b []byte) (n int, err error) {
+	if cc.up == nil {
+		return cc.write(b)
+	}
+	cc.up.delay()
+	for n < len(b) && err == nil {
+		var written int
+		written, err = cc.write(b[n : n+cc.up.chunk(len(b)-n)])
+		cc.up.pass(written)
+		n += written
+	}
+	return
+}
+
+func (cc *CountingConn) write(b []byte) (n int, err error) {
 	n, err = cc.Conn.Write(b)
 
 	if err == nil {
@@ -31,41 +66,272 @@ func (cc *CountingConn) Write(b []byte) (n int, err error) {
 	return
 }
 
+func (cc *CountingConn) Close() error {
+	cc.markClosed()
+	return cc.Conn.Close()
+}
+
+// markClosed tells if the connection wasn't known to be closed before.
+func (cc *CountingConn) markClosed() bool {
+	if !atomic.CompareAndSwapInt32(&cc.closed, 0, 1) {
+		return false
+	}
+	if cc.lifetime != nil {
+		cc.lifetime.Closed(cc)
+	}
+	return true
+}
+
 var FasthttpDialFunc = func(
-	bytesRead, bytesWritten *int64,
+	opts *ClientOpts,
 ) func(string) (net.Conn, error) {
+	dial := HttpDialContextFunc(opts)
+	if strings.HasPrefix(opts.url, "https://") {
+		dial = HttpDialTLSContextFunc(opts, opts.tlsConfig)
+	}
 	return func(address string) (net.Conn, error) {
-		conn, err := net.Dial("tcp", address)
+		return dial(context.Background(), "tcp", address)
+	}
+}
+
+var HttpDialTLSContextFunc = func(
+	opts *ClientOpts, tlsConfig *tls.Config,
+) func(context.Context, string, string) (net.Conn, error) {
+	dial := HttpDialContextFunc(opts)
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := dial(ctx, network, address)
 		if err != nil {
 			return nil, err
 		}
+		return TLSClientHandshake(conn, tlsConfig, address, opts)
+	}
+}
 
-		wrappedConn := &CountingConn{
-			Conn:         conn,
-			bytesRead:    bytesRead,
-			bytesWritten: bytesWritten,
+// TLSClientHandshake performs TLS handshake over conn established to
+// address and records negotiated parameters.
+func TLSClientHandshake(
+	conn net.Conn, tlsConfig *tls.Config, address string, opts *ClientOpts,
+) (net.Conn, error) {
+	var cfg *tls.Config
+	if tlsConfig != nil {
+		cfg = tlsConfig.Clone()
+	} else {
+		cfg = &tls.Config{}
+	}
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
 		}
-
-		return wrappedConn, nil
+		cfg.ServerName = host
+	}
+	if opts.identities != nil {
+		identity := opts.identities.Next()
+		cfg.Certificates = []tls.Certificate{
+			opts.identities.Certificate(identity),
+		}
+		conn = opts.identities.Bind(conn, identity)
+	}
+	tlsConn := tls.Client(conn, cfg)
+	if opts.timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(opts.timeout)); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	start := time.Now()
+	if err := tlsConn.Handshake(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	usTaken := uint64(time.Since(start).Nanoseconds() / 1000)
+	if opts.timeout > 0 {
+		if err := conn.SetDeadline(time.Time{}); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	if opts.tlsStats != nil {
+		opts.tlsStats.Add(tlsConn.ConnectionState(), usTaken)
 	}
+	return tlsConn, nil
 }
 
 var HttpDialContextFunc = func(
-	bytesRead, bytesWritten *int64,
+	opts *ClientOpts,
 ) func(context.Context, string, string) (net.Conn, error) {
-	dialer := &net.Dialer{}
+	resolver := NewTargetResolver(opts)
+	var localAddrs []net.IP
+	if opts.localAddrs != nil {
+		for _, addr := range *opts.localAddrs {
+			localAddrs = append(localAddrs, net.ParseIP(addr))
+		}
+	}
+	nextLocalAddr := uint64(0)
+	var proxy *url.URL
+	tunnel := false
+	if opts.proxy != "" {
+		var err error
+		proxy, err = url.Parse(opts.proxy)
+		if err != nil {
+			// opts.proxy guaranteed to be valid at this point
+			panic(err)
+		}
+		target, err := url.Parse(opts.url)
+		if err != nil {
+			// opts.url guaranteed to be valid at this point
+			panic(err)
+		}
+		tunnel = !ForwardsThroughProxy(proxy, target)
+	}
 	return func(ctx context.Context, network, address string) (net.Conn, error) {
-		conn, err := dialer.DialContext(ctx, network, address)
+		var (
+			addrs   []string
+			localIP net.IP
+			err     error
+		)
+		start := time.Now()
+		// When the target listens on a Unix domain socket the address
+		// derived from URL is only used for Host header and SNI.
+		if opts.unixSocket != "" {
+			network, addrs = "unix", []string{opts.unixSocket}
+		} else {
+			ipVersion := opts.ipVersion
+			if len(localAddrs) > 0 {
+				i := (atomic.AddUint64(&nextLocalAddr, 1) - 1) %
+					uint64(len(localAddrs))
+				localIP = localAddrs[i]
+				ipVersion = IPVersionOf(localIP)
+			}
+			network = TCPNetwork(ipVersion)
+			if proxy != nil {
+				// Target's address is resolved by the proxy.
+				addrs = []string{ProxyAddr(proxy)}
+			} else {
+				addrs, err = resolver.AddrsOfVersion(ctx, address, ipVersion)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		dialer := &net.Dialer{}
+		if localIP != nil {
+			dialer.LocalAddr = &net.TCPAddr{IP: localIP}
+		}
+		var conn net.Conn
+		for _, addr := range addrs {
+			conn, err = dialer.DialContext(ctx, network, addr)
+			if err == nil {
+				break
+			}
+		}
 		if err != nil {
+			if localIP != nil {
+				err = &LocalAddrError{localAddr: localIP.String(), err: err}
+			}
 			return nil, err
 		}
 
 		wrappedConn := &CountingConn{
 			Conn:         conn,
-			bytesRead:    bytesRead,
-			bytesWritten: bytesWritten,
+			bytesRead:    opts.bytesRead,
+			bytesWritten: opts.bytesWritten,
+			stats:        opts.connStats,
+			lifetime:     opts.lifetime,
+		}
+		if opts.bandwidth != nil || opts.connLatency > 0 {
+			wrappedConn.up, wrappedConn.down = newLinks(
+				opts.bandwidth, opts.connLatency,
+			)
+		}
+		if opts.connStats != nil {
+			atomic.AddUint64(&opts.connStats.opened, 1)
+		}
+		if opts.lifetime != nil {
+			opts.lifetime.Opened(wrappedConn)
+		}
+
+		if proxy != nil {
+			if tunnel {
+				err = ProxyHandshake(wrappedConn, proxy,
+					resolver.connectTo.Apply(address), opts.timeout)
+				if err != nil {
+					_ = wrappedConn.Close()
+					return nil, err
+				}
+			}
+			if opts.proxyLatencies != nil {
+				opts.proxyLatencies.Increment(
+					uint64(time.Since(start).Nanoseconds() / 1000),
+				)
+			}
 		}
 
 		return wrappedConn, nil
 	}
 }
+
+// LocalAddrError is returned when dialing from one of the
+// user-specified local addresses fails, so that errors are
+// reported separately for each of them.
+type LocalAddrError struct {
+	localAddr string
+	err       error
+}
+
+func (l *LocalAddrError) Error() string {
+	return fmt.Sprintf("local address %v: %v", l.localAddr, l.err)
+}
+
+func (l *LocalAddrError) Unwrap() error {
+	return l.err
+}
+
+// LocalAddrList is a list of local IP addresses to establish
+// connections from.
+type LocalAddrList []string
+
+func (l *LocalAddrList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *LocalAddrList) IsCumulative() bool {
+	return true
+}
+
+func (l *LocalAddrList) Set(value string) error {
+	for _, addr := range strings.Split(value, ",") {
+		addr = strings.TrimSpace(addr)
+		if net.ParseIP(addr) == nil {
+			return fmt.Errorf("%q is not a valid local IP address", addr)
+		}
+		*l = append(*l, addr)
+	}
+	return nil
+}
+
+const (
+	anyIPVersion = 0
+	ipv4         = 4
+	ipv6         = 6
+)
+
+// IPVersionOf returns 4 or 6 depending on the family of ip.
+func IPVersionOf(ip net.IP) int {
+	if ip.To4() != nil {
+		return ipv4
+	}
+	return ipv6
+}
+
+// TCPNetwork returns the network name to dial for the given IP version.
+func TCPNetwork(ipVersion int) string {
+	switch ipVersion {
+	case ipv4:
+		return "tcp4"
+	case ipv6:
+		return "tcp6"
+	}
+	return "tcp"
+}
@@ -0,0 +1,144 @@
+package bombardier
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLocalAddrListParsing(t *testing.T) {
+	l := new(LocalAddrList)
+	for _, v := range []string{"10.0.0.1, 10.0.0.2", "::1"} {
+		if err := l.Set(v); err != nil {
+			t.Error(v, err)
+		}
+	}
+	e := LocalAddrList{"10.0.0.1", "10.0.0.2", "::1"}
+	if !reflect.DeepEqual(*l, e) {
+		t.Errorf("Expected %v, but got %v", e, *l)
+	}
+	if err := l.Set("10.0.0.1,localhost"); err == nil {
+		t.Error("Should fail on invalid IP addresses")
+	}
+}
+
+func TestTCPNetwork(t *testing.T) {
+	expectations := []struct {
+		ip      string
+		network string
+	}{
+		{"127.0.0.1", "tcp4"},
+		{"::1", "tcp6"},
+	}
+	for _, e := range expectations {
+		n := TCPNetwork(IPVersionOf(net.ParseIP(e.ip)))
+		if n != e.network {
+			t.Errorf("Expected %v for %v, but got %v", e.network, e.ip, n)
+		}
+	}
+	if n := TCPNetwork(anyIPVersion); n != "tcp" {
+		t.Errorf("Expected tcp, but got %v", n)
+	}
+}
+
+func TestDialerRotatesLocalAddrs(t *testing.T) {
+	ln, err := net.Listen("tcp4", "0.0.0.0:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, aerr := ln.Accept()
+			if aerr != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	bytesRead, bytesWritten := int64(0), int64(0)
+	localAddrs := LocalAddrList{"127.0.0.1", "127.0.0.2"}
+	dial := HttpDialContextFunc(&ClientOpts{
+		localAddrs:   &localAddrs,
+		bytesRead:    &bytesRead,
+		bytesWritten: &bytesWritten,
+	})
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	seen := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		conn, derr := dial(
+			context.Background(), "tcp", net.JoinHostPort("127.0.0.1", port),
+		)
+		if derr != nil {
+			t.Fatal(derr)
+		}
+		host, _, _ := net.SplitHostPort(conn.LocalAddr().String())
+		seen[host]++
+		conn.Close()
+	}
+	e := map[string]int{"127.0.0.1": 2, "127.0.0.2": 2}
+	if !reflect.DeepEqual(seen, e) {
+		t.Errorf("Expected %v, but got %v", e, seen)
+	}
+}
+
+func TestDialerMatchesTargetToLocalAddrVersion(t *testing.T) {
+	// Listener on the unspecified IPv6 address accepts both IPv4 and
+	// IPv6 connections
+	ln, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Skip("IPv6 isn't available:", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, aerr := ln.Accept()
+			if aerr != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	target := net.JoinHostPort("example.com", port)
+	bytesRead, bytesWritten := int64(0), int64(0)
+	localAddrs := LocalAddrList{"127.0.0.1", "::1"}
+	resolve := ResolveList{target: {"127.0.0.1", "::1"}}
+	dial := HttpDialContextFunc(&ClientOpts{
+		localAddrs:   &localAddrs,
+		resolve:      &resolve,
+		spreadAddrs:  true,
+		bytesRead:    &bytesRead,
+		bytesWritten: &bytesWritten,
+	})
+	for i := 0; i < 4; i++ {
+		conn, derr := dial(context.Background(), "tcp", target)
+		if derr != nil {
+			t.Fatal(derr)
+		}
+		local, _, _ := net.SplitHostPort(conn.LocalAddr().String())
+		remote, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+		if IPVersionOf(net.ParseIP(local)) !=
+			IPVersionOf(net.ParseIP(remote)) {
+			t.Errorf("connection from %v to %v", local, remote)
+		}
+		conn.Close()
+	}
+}
+
+func TestDialerReportsLocalAddrInErrors(t *testing.T) {
+	localAddrs := LocalAddrList{"192.0.2.1"}
+	dial := HttpDialContextFunc(&ClientOpts{
+		localAddrs: &localAddrs,
+	})
+	_, err := dial(context.Background(), "tcp", "127.0.0.1:1")
+	if err == nil {
+		t.Fatal("Expected dial from unassigned address to fail")
+	}
+	if _, ok := err.(*LocalAddrError); !ok ||
+		!strings.HasPrefix(err.Error(), "local address 192.0.2.1: ") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -9,53 +9,186 @@ Usage:
   bombardier [<flags>] <url>
 
 Flags:
-      --help                  Show context-sensitive help (also try --help-long
-                              and --help-man).
-      --version               Show application version.
-  -c, --connections=125       Maximum number of concurrent connections
-  -t, --timeout=2s            Socket/request timeout
-  -l, --latencies             Print latency statistics
-  -m, --method=GET            Request method
-  -b, --body=""               Request body
-  -f, --body-file=""          File to use as request body
-  -s, --stream                Specify whether to stream body using chunked
-                              transfer encoding or to serve it from memory
-      --cert=""               Path to the Client's TLS Certificate
-      --key=""                Path to the Client's TLS Certificate Private Key
-  -k, --insecure              Controls whether a Client verifies the server's
-                              certificate chain and host name
-  -H, --Header="K: V" ...     HTTP headers to use(can be repeated)
-  -n, --requests=[pos. int.]  Number of requests
-  -d, --duration=10s          Duration of test
-  -r, --rate=[pos. int.]      Rate limit in requests per second
-      --fasthttp              Use fasthttp Client
-      --http1                 Use net/http Client with forced HTTP/1.x
-      --http2                 Use net/http Client with enabled HTTP/2.0
-  -p, --print=<spec>          Specifies what to output. Comma-separated list of
-                              values 'intro' (short: 'i'), 'progress' (short:
-                              'p'), 'result' (short: 'r'). Examples:
-
-                                * i,p,r (prints everything)
-                                * intro,result (intro & result)
-                                * r (result only)
-                                * result (same as above)
-  -q, --no-print              Don't output anything
-  -o, --Format=<spec>         Which Format to use to output the result. <spec>
-                              is either a name (or its shorthand) of some Format
-                              understood by bombardier or a path to the
-                              user-defined Template, which uses Go's
-                              text/Template syntax, prefixed with 'path:' string
-                              (without single quotes), i.e.
-                              "path:/some/path/to/your.Template" or
-                              "path:C:\some\path\to\your.Template" in case of
-                              Windows. Formats understood by bombardier are:
-
-                                * plain-text (short: pt)
-                                * json (short: j)
+      --help                     Show context-sensitive help (also try
+                                 --help-long and --help-man).
+      --version                  Show application version.
+  -c, --connections=125          Maximum number of concurrent connections
+  -t, --timeout=2s               Socket/request timeout
+  -l, --latencies                Print latency statistics
+  -m, --method=GET               Request method
+  -b, --body=""                  Request body
+  -f, --body-file=""             File to use as request body
+      --form=key=value|key=@path ...
+                                 Form field to send, values starting
+                                 with @ are paths of files to upload as
+                                 multipart/form-data, forms without files are
+                                 URL-encoded(can be repeated)
+      --compress-body=gzip|deflate|br|zstd
+                                 Compress request body once before the test and
+                                 send it with Content-Encoding header
+      --accept-encoding=<encodings>
+                                 Request compressed responses by sending this
+                                 Accept-Encoding header
+      --decompress               Decompress gzip, deflate, br and zstd response
+                                 bodies and report decompressed byte counts and
+                                 decompression errors
+  -s, --stream                   Specify whether to stream body using chunked
+                                 transfer encoding or to serve it from memory
+      --cert=""                  Path to the Client's TLS Certificate,
+                                 comma-separated list of paths or directory with
+                                 certificate and key pairs, which are rotated
+                                 across connections
+      --key=""                   Path to the Client's TLS Certificate Private
+                                 Key, comma-separated list of paths or directory
+                                 with keys
+      --cacert=<path>            Path to the CA certificates bundle to verify
+                                 server's certificate against
+      --sni=<name>               Server name to send in TLS handshake and to
+                                 verify server's certificate against
+      --tls-min=<version>        Minimum TLS version (1.0, 1.1, 1.2 or 1.3)
+      --tls-max=<version>        Maximum TLS version (1.0, 1.1, 1.2 or 1.3)
+      --ciphers=<list>           Comma-separated list of TLS
+                                 1.0-1.2 cipher suites, i.e.
+                                 TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+      --curves=<list>            Comma-separated list of elliptic curves in
+                                 order of preference (X25519, P256, P384, P521)
+      --alpn=<list>              Comma-separated list of application protocols
+                                 to advertise in TLS handshake
+      --tls-resumption=off|tickets|cache
+                                 TLS session resumption mode: off (full
+                                 handshake every time), tickets (each session
+                                 ticket is used once) or cache (sessions are
+                                 shared by all connections)
+      --tls-keylog=<path>        File to append TLS secrets to in NSS key
+                                 log format, so that captured traffic can be
+                                 decrypted
+      --unix-socket=<path>       Path to the Unix domain socket to connect to
+                                 instead of the host from URL
+      --connect-to=HOST1:PORT1:HOST2:PORT2 ...
+                                 Connect to HOST2:PORT2 instead of HOST1:PORT1,
+                                 either of hosts and ports may be empty(can be
+                                 repeated)
+      --resolve=HOST:PORT:ADDR[,ADDR]... ...
+                                 Use static addresses for HOST:PORT instead of
+                                 system resolver(can be repeated)
+      --spread-addrs             Round-robin new connections across all
+                                 addresses the target resolves to and report
+                                 per-address statistics
+      --local-addr=IP[,IP]... ...
+                                 Local IP address to establish connections from,
+                                 connections rotate across all of them(can be
+                                 repeated)
+  -4, --ipv4                     Use IPv4 addresses only
+  -6, --ipv6                     Use IPv6 addresses only
+      --proxy=<url>              Proxy to send requests through,
+                                 either http://[user:pass@]host:port or
+                                 socks5://[user:pass@]host:port
+      --basic=user:pass          Credentials for HTTP Basic authentication
+      --bearer-file=<path>       File to read Bearer token from, the file is
+                                 reread when it changes
+      --hmac-sha256=key,header   Sign every request with HMAC-SHA256 of method,
+                                 request URI, timestamp and body joined with
+                                 newlines, timestamp is sent in X-Timestamp
+                                 header
+      --aws-sigv4=region/service
+                                 Sign every request with AWS Signature Version
+                                 4 using credentials from AWS_* environment
+                                 variables
+      --oauth2-token-url=<url>   OAuth2 token endpoint to obtain access tokens
+                                 from using client credentials grant, tokens are
+                                 refreshed before they expire
+      --client-id=<id>           OAuth2 client ID
+      --client-secret=<secret>   OAuth2 client secret
+      --scope=<scopes>           Space-separated list of OAuth2 scopes to
+                                 request
+      --cookies=shared|worker    Keep cookies set by the server either in a
+                                 single jar shared by all connections or in a
+                                 separate jar for each worker, so that every
+                                 worker keeps its own session
+      --scenario=<file>          JSON file with an ordered list of steps every
+                                 worker performs in each iteration, values
+                                 extracted from responses can be referenced as
+                                 ${name} by subsequent steps. Number of requests
+                                 and rate apply to iterations then
+      --think-time=DURATION|uniform:MIN,MAX|normal:MEAN,STDDEV|exponential:MEAN
+                                 Time each worker waits after every request,
+                                 either fixed or drawn from uniform, normal or
+                                 exponential distribution
+      --pacing=<duration>        Minimum period of each request(or scenario
+                                 iteration) per worker, including think time
+      --pipeline=N               Pipeline up to N requests over each
+                                 connection(fasthttp only)
+      --max-conn-requests=N      Reconnect after sending N requests over
+                                 a connection, each worker owns a single
+                                 connection then
+      --max-conn-age=<duration>  Reconnect once a connection gets older than the
+                                 duration, each worker owns a single connection
+                                 then
+      --streams-per-connection=N
+                                 Number of concurrent streams multiplexed over
+                                 each connection, workers are pinned to the
+                                 connections then(net/http v2.0 and https://
+                                 targets only)
+      --bandwidth-limit=RATE|UP,DOWN
+                                 Limit rate of data sent and received over each
+                                 connection, either the same for both directions
+                                 or separate for upload and download, in B, KB,
+                                 MB, GB or bps, kbps, mbps, gbps per second
+      --conn-latency=<duration>  Delay each read from and write to connections
+                                 by the duration
+      --sample-responses=N       Keep the first N and a random sample of N other
+                                 responses with each status code and occurrences
+                                 of each error, bodies are truncated to 4KB
+      --sample-dir=<dir>         Write sampled responses to files in the
+                                 directory instead of embedding them into the
+                                 output
+      --trace-log=<file>         Write each request, its status, latency,
+                                 sizes and error to the file as a line of JSON,
+                                 entries are dropped rather than slowing the
+                                 requests down
+      --error-histogram          Count occurrences of each error during every
+                                 second of the test
+      --status-latencies         Compute latencies of responses with each status
+                                 code separately
+  -k, --insecure                 Controls whether a Client verifies the server's
+                                 certificate chain and host name
+  -a, --disableKeepAlives        Disable HTTP keep-alive. For fasthttp use -H
+                                 'Connection: close'
+  -H, --Header="K: V" ...        HTTP headers to use(can be repeated)
+  -n, --requests=[pos. int.]     Number of requests
+  -d, --duration=10s             Duration of test
+  -r, --rate=[pos. int.]         Rate limit in requests per second
+      --fasthttp                 Use fasthttp Client
+      --http1                    Use net/http Client with forced HTTP/1.x
+      --http2                    Use net/http Client with enabled HTTP/2.0
+  -p, --print=<spec>             Specifies what to output. Comma-separated list
+                                 of values 'intro' (short: 'i'), 'progress'
+                                 (short: 'p'), 'result' (short: 'r'). Examples:
+                                   - i,p,r (prints everything)
+                                   - intro,result (intro & result)
+                                   - r (result only)
+                                   - result (same as above)
+  -q, --no-print                 Don't output anything
+  -o, --Format=<spec>            Which Format to use to output the result.
+                                 <spec> is either a name (or its shorthand)
+                                 of some Format understood by bombardier
+                                 or a path to the user-defined Template,
+                                 which uses Go's text/Template syntax, prefixed
+                                 with 'path:' string (without single quotes),
+                                 i.e. "path:/some/path/to/your.Template" or
+                                 "path:C:\some\path\to\your.Template" in case of
+                                 Windows. Formats understood by bombardier are:
+                                   - plain-text (short: pt)
+                                   - json (short: j)
 
 Args:
   <url>  Target's URL
 
+Targets listening on Unix domain sockets can be specified either with
+--unix-socket flag or with http+unix:// (https+unix://) URL, where host
+is the percent-encoded path to the socket, i.e.
+  bombardier http+unix://%2Fvar%2Frun%2Fapp.sock/status
+
 For detailed documentation on user-defined templates see
 documentation for package github.com/codesenberg/bombardier/Template.
 Link (GoDoc):
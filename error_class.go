@@ -0,0 +1,96 @@
+package bombardier
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/gho1b/bombardier/internal"
+	"github.com/valyala/fasthttp"
+)
+
+// bodyReadError is returned when the response was received, but its
+// body failed to be read or decoded.
+type bodyReadError struct {
+	err error
+}
+
+func (e *bodyReadError) Error() string {
+	return e.err.Error()
+}
+
+func (e *bodyReadError) Unwrap() error {
+	return e.err
+}
+
+// assertionError is returned when the response doesn't meet the
+// expectations, e.g. a value that had to be extracted from it is
+// missing.
+type assertionError struct {
+	msg string
+}
+
+func (e *assertionError) Error() string {
+	return e.msg
+}
+
+// ClassifyError returns category err falls into. Errors are classified
+// by where they happened first and by their cause second, so that a
+// timeout while reading the body is a body read error.
+func ClassifyError(err error) internal.ErrorClass {
+	var (
+		assertion *assertionError
+		bodyRead  *bodyReadError
+		dns       *net.DNSError
+		netErr    net.Error
+	)
+	switch {
+	case errors.As(err, &assertion):
+		return internal.ErrorAssertion
+	case errors.As(err, &bodyRead):
+		return internal.ErrorBodyRead
+	case errors.As(err, &dns):
+		return internal.ErrorDNS
+	case errors.Is(err, fasthttp.ErrTimeout),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return internal.ErrorTimeout
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		switch errno {
+		case syscall.ECONNREFUSED:
+			return internal.ErrorRefused
+		case syscall.ECONNRESET, syscall.EPIPE:
+			return internal.ErrorReset
+		}
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, fasthttp.ErrConnectionClosed) {
+		return internal.ErrorReset
+	}
+	if isTLSError(err) {
+		return internal.ErrorTLS
+	}
+	return internal.ErrorOther
+}
+
+func isTLSError(err error) bool {
+	var (
+		record    tls.RecordHeaderError
+		authority x509.UnknownAuthorityError
+		invalid   x509.CertificateInvalidError
+		hostname  x509.HostnameError
+	)
+	if errors.As(err, &record) || errors.As(err, &authority) ||
+		errors.As(err, &invalid) || errors.As(err, &hostname) {
+		return true
+	}
+	// Alerts are of unexported type, but their messages are prefixed
+	return strings.Contains(err.Error(), "tls: ")
+}
@@ -0,0 +1,66 @@
+package bombardier
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/gho1b/bombardier/internal"
+	"github.com/valyala/fasthttp"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyError(t *testing.T) {
+	dial := func(err error) error {
+		return &url.Error{
+			Op:  "Get",
+			URL: "http://localhost:8080",
+			Err: &net.OpError{Op: "dial", Net: "tcp", Err: err},
+		}
+	}
+	expectations := []struct {
+		err   error
+		class internal.ErrorClass
+	}{
+		{errors.New("something"), internal.ErrorOther},
+		{fasthttp.ErrTimeout, internal.ErrorTimeout},
+		{context.DeadlineExceeded, internal.ErrorTimeout},
+		{dial(timeoutError{}), internal.ErrorTimeout},
+		{dial(os.NewSyscallError("connect", syscall.ECONNREFUSED)), internal.ErrorRefused},
+		{dial(os.NewSyscallError("read", syscall.ECONNRESET)), internal.ErrorReset},
+		{fmt.Errorf("write: %w", syscall.EPIPE), internal.ErrorReset},
+		{&url.Error{Op: "Get", URL: "http://localhost", Err: io.EOF}, internal.ErrorReset},
+		{fasthttp.ErrConnectionClosed, internal.ErrorReset},
+		{dial(x509.UnknownAuthorityError{}), internal.ErrorTLS},
+		{errors.New("remote error: tls: handshake failure"), internal.ErrorTLS},
+		{dial(&net.DNSError{Name: "x", IsTimeout: true}), internal.ErrorDNS},
+		{&bodyReadError{timeoutError{}}, internal.ErrorBodyRead},
+		{&assertionError{"login: failed to extract token"}, internal.ErrorAssertion},
+	}
+	for _, e := range expectations {
+		if class := ClassifyError(e.err); class != e.class {
+			t.Errorf("expected %q to be %v, but got %v", e.err, e.class, class)
+		}
+	}
+}
+
+func TestErrorClassString(t *testing.T) {
+	if s := internal.ErrorRefused.String(); s != "connection refused" {
+		t.Errorf("expected %q, but got %q", "connection refused", s)
+	}
+	if s := internal.ErrorClass(-1).String(); s != "unknown" {
+		t.Errorf("expected %q, but got %q", "unknown", s)
+	}
+}
@@ -1,49 +1,141 @@
 package bombardier
 
 import (
+	"errors"
+	"fmt"
 	"sort"
 	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/gho1b/bombardier/internal"
 )
 
+// errorKey identifies errors counted together: errors are grouped by
+// their class or, if they aren't classified, by their messages. Errors
+// of connections from user-specified local addresses are counted
+// separately for each of them.
+type errorKey struct {
+	class     internal.ErrorClass
+	msg       string
+	localAddr string
+}
+
+// String returns representation of the key, that is different for
+// every key.
+func (k errorKey) String() string {
+	return fmt.Sprintf("%v %q %q", k.class, k.msg, k.localAddr)
+}
+
+// errorCount counts errors with the same key, example is the message
+// of the first one of them. Times they were first and last seen are in
+// nanoseconds since the epoch.
+type errorCount struct {
+	example     string
+	count       uint64
+	first, last int64
+
+	mu        sync.Mutex
+	perSecond []uint64
+}
+
+// seen records error that occurred at now(in nanoseconds since the
+// epoch) and, if perSecond is set, counts it in its second since begin.
+func (c *errorCount) seen(now, begin int64, perSecond bool) {
+	atomic.AddUint64(&c.count, 1)
+	for first := atomic.LoadInt64(&c.first); now < first; {
+		if atomic.CompareAndSwapInt64(&c.first, first, now) {
+			break
+		}
+		first = atomic.LoadInt64(&c.first)
+	}
+	for last := atomic.LoadInt64(&c.last); now > last; {
+		if atomic.CompareAndSwapInt64(&c.last, last, now) {
+			break
+		}
+		last = atomic.LoadInt64(&c.last)
+	}
+	if !perSecond {
+		return
+	}
+	second := 0
+	if now > begin {
+		second = int((now - begin) / int64(time.Second))
+	}
+	c.mu.Lock()
+	for len(c.perSecond) <= second {
+		c.perSecond = append(c.perSecond, 0)
+	}
+	c.perSecond[second]++
+	c.mu.Unlock()
+}
+
 type ErrorMap struct {
-	mu sync.RWMutex
-	m  map[string]*uint64
+	mu        sync.RWMutex
+	m         map[errorKey]*errorCount
+	begin     int64
+	perSecond bool
 }
 
 func NewErrorMap() *ErrorMap {
 	em := new(ErrorMap)
-	em.m = make(map[string]*uint64)
+	em.m = make(map[errorKey]*errorCount)
+	em.begin = time.Now().UnixNano()
 	return em
 }
 
+// Start sets the time errors are timed relative to, which is the time
+// the map was created at by default, and whether they are counted per
+// second since then.
+func (e *ErrorMap) Start(begin time.Time, perSecond bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.begin, e.perSecond = begin.UnixNano(), perSecond
+}
+
+func keyOf(err error) errorKey {
+	class := ClassifyError(err)
+	if class == internal.ErrorOther {
+		return errorKey{class: class, msg: err.Error()}
+	}
+	k := errorKey{class: class}
+	var lae *LocalAddrError
+	if errors.As(err, &lae) {
+		k.localAddr = lae.localAddr
+	}
+	return k
+}
+
 func (e *ErrorMap) Add(err error) {
-	s := err.Error()
+	k := keyOf(err)
+	now := time.Now().UnixNano()
 	e.mu.RLock()
-	c, ok := e.m[s]
+	c, ok := e.m[k]
+	begin, perSecond := e.begin, e.perSecond
 	e.mu.RUnlock()
 	if !ok {
 		e.mu.Lock()
-		c, ok = e.m[s]
+		c, ok = e.m[k]
 		if !ok {
-			c = new(uint64)
-			e.m[s] = c
+			c = &errorCount{example: err.Error(), first: now, last: now}
+			e.m[k] = c
 		}
 		e.mu.Unlock()
 	}
-	atomic.AddUint64(c, 1)
+	c.seen(now, begin, perSecond)
 }
 
+// Get returns number of errors counted together with err.
 func (e *ErrorMap) Get(err error) uint64 {
-	s := err.Error()
+	k := keyOf(err)
 	e.mu.RLock()
 	defer e.mu.RUnlock()
-	c := e.m[s]
+	c := e.m[k]
 	if c == nil {
 		return uint64(0)
 	}
-	return *c
+	return atomic.LoadUint64(&c.count)
 }
 
 func (e *ErrorMap) Sum() uint64 {
@@ -51,18 +143,25 @@ func (e *ErrorMap) Sum() uint64 {
 	defer e.mu.RUnlock()
 	sum := uint64(0)
 	for _, v := range e.m {
-		sum += *v
+		sum += atomic.LoadUint64(&v.count)
 	}
 	return sum
 }
 
+// ErrorWithCount contains class of the errors, message of one of them
+// and number of them alongside with times, relative to the start of the
+// test, they were first and last seen at and, if they were counted, the
+// number of them during each second of the test.
 type ErrorWithCount struct {
-	error string
-	count uint64
+	class       internal.ErrorClass
+	error       string
+	count       uint64
+	first, last time.Duration
+	perSecond   []uint64
 }
 
 func (ewc *ErrorWithCount) String() string {
-	return "<" + ewc.error + ":" +
+	return "<" + ewc.class.String() + ":" + ewc.error + ":" +
 		strconv.FormatUint(ewc.count, decBase) + ">"
 }
 
@@ -83,10 +182,31 @@ func (ebf ErrorsByFrequency) Swap(i, j int) {
 func (e *ErrorMap) ByFrequency() ErrorsByFrequency {
 	e.mu.RLock()
 	byFreq := make(ErrorsByFrequency, 0, len(e.m))
-	for err, count := range e.m {
-		byFreq = append(byFreq, &ErrorWithCount{err, *count})
+	for k, c := range e.m {
+		ewc := &ErrorWithCount{
+			class: k.class,
+			error: c.example,
+			count: atomic.LoadUint64(&c.count),
+			first: sinceBegin(atomic.LoadInt64(&c.first), e.begin),
+			last:  sinceBegin(atomic.LoadInt64(&c.last), e.begin),
+		}
+		if e.perSecond {
+			c.mu.Lock()
+			ewc.perSecond = append([]uint64(nil), c.perSecond...)
+			c.mu.Unlock()
+		}
+		byFreq = append(byFreq, ewc)
 	}
 	e.mu.RUnlock()
 	sort.Sort(byFreq)
 	return byFreq
 }
+
+// sinceBegin returns time passed between begin and t, errors that
+// occurred before the test began are at its very beginning.
+func sinceBegin(t, begin int64) time.Duration {
+	if t < begin {
+		return 0
+	}
+	return time.Duration(t - begin)
+}
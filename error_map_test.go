@@ -2,8 +2,14 @@ package bombardier
 
 import (
 	"errors"
+	"net"
+	"os"
 	"reflect"
+	"syscall"
 	"testing"
+	"time"
+
+	"github.com/gho1b/bombardier/internal"
 )
 
 func TestErrorMapAdd(t *testing.T) {
@@ -35,20 +41,113 @@ func TestByFrequency(t *testing.T) {
 	m.Add(b)
 	m.Add(c)
 	e := ErrorsByFrequency{
-		{"B", 3},
-		{"A", 2},
-		{"C", 1},
+		{class: internal.ErrorOther, error: "B", count: 3},
+		{class: internal.ErrorOther, error: "A", count: 2},
+		{class: internal.ErrorOther, error: "C", count: 1},
+	}
+	byFreq := m.ByFrequency()
+	for _, ewc := range byFreq {
+		ewc.first, ewc.last = 0, 0
 	}
-	if a := m.ByFrequency(); !reflect.DeepEqual(a, e) {
+	if !reflect.DeepEqual(byFreq, e) {
 		t.Logf("Expected: %+v", e)
-		t.Logf("Got: %+v", a)
+		t.Logf("Got: %+v", byFreq)
 		t.Fail()
 	}
 }
 
+func TestErrorMapGroupsClassifiedErrors(t *testing.T) {
+	m := NewErrorMap()
+	var messages []string
+	for i := 0; i < 3; i++ {
+		err := &net.OpError{
+			Op:  "dial",
+			Net: "tcp",
+			Err: os.NewSyscallError("connect", syscall.ECONNREFUSED),
+			Addr: &net.TCPAddr{
+				IP: net.IPv4(127, 0, 0, 1), Port: 8080 + i,
+			},
+		}
+		messages = append(messages, err.Error())
+		m.Add(err)
+	}
+	if messages[0] == messages[1] || messages[1] == messages[2] {
+		t.Fatalf("expected distinct messages, but got %q", messages)
+	}
+	m.Add(errors.New("A"))
+	byFreq := m.ByFrequency()
+	if len(byFreq) != 2 {
+		t.Fatalf("expected 2 entries, but got %v", byFreq)
+	}
+	ewc := byFreq[0]
+	if ewc.class != internal.ErrorRefused || ewc.count != 3 {
+		t.Errorf("expected 3 refused connections, but got %v", ewc)
+	}
+	if ewc.error != messages[0] {
+		t.Errorf("expected example %q, but got %q", messages[0], ewc.error)
+	}
+	if m.Sum() != 4 {
+		t.Errorf("expected 4 errors, but got %v", m.Sum())
+	}
+}
+
+func TestErrorMapKeepsLocalAddrsApart(t *testing.T) {
+	m := NewErrorMap()
+	for _, la := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.1"} {
+		m.Add(&LocalAddrError{
+			localAddr: la,
+			err:       os.NewSyscallError("connect", syscall.ECONNREFUSED),
+		})
+	}
+	byFreq := m.ByFrequency()
+	if len(byFreq) != 2 {
+		t.Fatalf("expected 2 entries, but got %v", byFreq)
+	}
+	for _, ewc := range byFreq {
+		if ewc.class != internal.ErrorRefused {
+			t.Errorf("expected refused connections, but got %v", ewc)
+		}
+	}
+	if byFreq[0].count != 2 || byFreq[0].error !=
+		"local address 10.0.0.1: connect: connection refused" {
+		t.Errorf("unexpected entry %v", byFreq[0])
+	}
+}
+
+func TestErrorMapTimeline(t *testing.T) {
+	m := NewErrorMap()
+	begin := time.Now().Add(-2500 * time.Millisecond)
+	m.Start(begin, true)
+	err := errors.New("A")
+	m.Add(err)
+	m.Add(err)
+	byFreq := m.ByFrequency()
+	if len(byFreq) != 1 {
+		t.Fatalf("expected 1 entry, but got %v", byFreq)
+	}
+	ewc := byFreq[0]
+	if ewc.first < 2500*time.Millisecond || ewc.last < ewc.first ||
+		ewc.last > time.Since(begin) {
+		t.Errorf("unexpected first(%v) and last(%v) occurrences",
+			ewc.first, ewc.last)
+	}
+	if !reflect.DeepEqual(ewc.perSecond, []uint64{0, 0, 2}) {
+		t.Errorf("expected 2 errors in the third second, but got %v",
+			ewc.perSecond)
+	}
+}
+
+func TestErrorMapWithoutHistogram(t *testing.T) {
+	m := NewErrorMap()
+	m.Add(errors.New("A"))
+	if ewc := m.ByFrequency()[0]; ewc.perSecond != nil {
+		t.Errorf("expected no histogram, but got %v", ewc.perSecond)
+	}
+}
+
 func TestErrorWithCountToStringConversion(t *testing.T) {
-	ewc := ErrorWithCount{"A", 1}
-	exp := "<A:1>"
+	ewc := ErrorWithCount{class: internal.ErrorOther, error: "A", count: 1}
+	exp := "<other:A:1>"
 	if act := ewc.String(); act != exp {
 		t.Logf("Expected: %+v", exp)
 		t.Logf("Got: %+v", act)
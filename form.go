@@ -0,0 +1,167 @@
+package bombardier
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const formURLEncoded = "application/x-www-form-urlencoded"
+
+// FormField is a single field of the form, file fields are uploaded
+// from the path given in value.
+type FormField struct {
+	key, value string
+	file       bool
+}
+
+// FormFields is an ordered list of form fields. Forms with files are
+// sent as multipart/form-data and as URL-encoded forms otherwise.
+type FormFields []FormField
+
+func (f *FormFields) String() string {
+	return strings.Join(f.Entries(), " ")
+}
+
+func (f *FormFields) IsCumulative() bool {
+	return true
+}
+
+func (f *FormFields) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return errInvalidFormFormat
+	}
+	field := FormField{key: parts[0], value: parts[1]}
+	if strings.HasPrefix(field.value, "@") {
+		field.value, field.file = field.value[1:], true
+		if field.value == "" {
+			return errInvalidFormFormat
+		}
+	}
+	*f = append(*f, field)
+	return nil
+}
+
+// Entries returns fields in the key=value or key=@path form.
+func (f *FormFields) Entries() []string {
+	entries := make([]string, 0, len(*f))
+	for _, field := range *f {
+		if field.file {
+			entries = append(entries, field.key+"=@"+field.value)
+		} else {
+			entries = append(entries, field.key+"="+field.value)
+		}
+	}
+	return entries
+}
+
+// Multipart tells if the form has to be sent as multipart/form-data.
+func (f FormFields) Multipart() bool {
+	for _, field := range f {
+		if field.file {
+			return true
+		}
+	}
+	return false
+}
+
+// Body returns the whole body of the form alongside with its
+// Content-Type.
+func (f FormFields) Body() (string, string, error) {
+	if !f.Multipart() {
+		return f.urlEncoded(), formURLEncoded, nil
+	}
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	if err := f.writeMultipart(w); err != nil {
+		return "", "", err
+	}
+	return buf.String(), w.FormDataContentType(), nil
+}
+
+// BodyStreamProducer returns producer of form bodies that reads files
+// only as the body is being sent alongside with Content-Type of the
+// bodies. All of the bodies share the same boundary.
+func (f FormFields) BodyStreamProducer() (
+	BodyStreamProducer, string, error,
+) {
+	if !f.Multipart() {
+		body := f.urlEncoded()
+		return func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(
+				ProxyReader{strings.NewReader(body)},
+			), nil
+		}, formURLEncoded, nil
+	}
+	// Report missing files right away instead of failing every request
+	for _, field := range f {
+		if !field.file {
+			continue
+		}
+		if _, err := os.Stat(field.value); err != nil {
+			return nil, "", err
+		}
+	}
+	boundary := multipart.NewWriter(ioutil.Discard).Boundary()
+	bsp := func() (io.ReadCloser, error) {
+		pr, pw := io.Pipe()
+		w := multipart.NewWriter(pw)
+		if err := w.SetBoundary(boundary); err != nil {
+			return nil, err
+		}
+		go func() {
+			// Writes fail once the reader is closed, so the goroutine
+			// never outlives the request
+			pw.CloseWithError(f.writeMultipart(w))
+		}()
+		return pr, nil
+	}
+	return bsp, "multipart/form-data; boundary=" + boundary, nil
+}
+
+func (f FormFields) urlEncoded() string {
+	pairs := make([]string, 0, len(f))
+	for _, field := range f {
+		pairs = append(pairs,
+			url.QueryEscape(field.key)+"="+url.QueryEscape(field.value))
+	}
+	return strings.Join(pairs, "&")
+}
+
+func (f FormFields) writeMultipart(w *multipart.Writer) error {
+	for _, field := range f {
+		if !field.file {
+			if err := w.WriteField(field.key, field.value); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeFormFile(w, field.key, field.value); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+func writeFormFile(w *multipart.Writer, key, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	part, err := w.CreateFormFile(key, filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(part, file); err != nil {
+		return fmt.Errorf("%v: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package bombardier
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormFieldsParsing(t *testing.T) {
+	expectations := []struct {
+		in  []string
+		out FormFields
+		err error
+	}{
+		{
+			[]string{"a=1", "b=x=y", "c=", "file=@testbody.txt"},
+			FormFields{
+				{key: "a", value: "1"},
+				{key: "b", value: "x=y"},
+				{key: "c", value: ""},
+				{key: "file", value: "testbody.txt", file: true},
+			},
+			nil,
+		},
+		{[]string{"novalue"}, nil, errInvalidFormFormat},
+		{[]string{"=value"}, nil, errInvalidFormFormat},
+		{[]string{"file=@"}, nil, errInvalidFormFormat},
+	}
+	for _, e := range expectations {
+		var (
+			f   FormFields
+			err error
+		)
+		for _, v := range e.in {
+			if err = f.Set(v); err != nil {
+				break
+			}
+		}
+		if err != e.err {
+			t.Errorf("%v: expected %v, but got %v", e.in, e.err, err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(f, e.out) {
+			t.Errorf("%v: expected %v, but got %v", e.in, e.out, f)
+		}
+		if err == nil && !reflect.DeepEqual(f.Entries(), e.in) {
+			t.Errorf("expected entries %v, but got %v", e.in, f.Entries())
+		}
+	}
+}
+
+func TestURLEncodedFormBody(t *testing.T) {
+	f := FormFields{
+		{key: "b", value: "hello world"},
+		{key: "a", value: "x&y=z"},
+	}
+	if f.Multipart() {
+		t.Error("expected form without files to be URL-encoded")
+	}
+	expected := "b=hello+world&a=x%26y%3Dz"
+	body, contentType, err := f.Body()
+	if err != nil || body != expected || contentType != formURLEncoded {
+		t.Errorf("unexpected body %q(%v), %v", body, contentType, err)
+	}
+	bsp, contentType, err := f.BodyStreamProducer()
+	if err != nil || contentType != formURLEncoded {
+		t.Fatalf("unexpected content type %v, %v", contentType, err)
+	}
+	rc, err := bsp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	streamed, err := ioutil.ReadAll(rc)
+	if err != nil || string(streamed) != expected {
+		t.Errorf("unexpected streamed body %q, %v", streamed, err)
+	}
+}
+
+func checkMultipartForm(t *testing.T, body, contentType string) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" {
+		t.Fatalf("unexpected content type %v, %v", contentType, err)
+	}
+	form, err := multipart.NewReader(
+		strings.NewReader(body), params["boundary"],
+	).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = form.RemoveAll()
+	}()
+	if v := form.Value["name"]; len(v) != 1 || v[0] != "bombardier" {
+		t.Errorf("unexpected name field %v", v)
+	}
+	files := form.File["file"]
+	if len(files) != 1 || files[0].Filename != "testbody.txt" {
+		t.Fatalf("unexpected file field %v", files)
+	}
+	file, err := files[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ioutil.ReadFile("testbody.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != string(expected) {
+		t.Errorf("expected file content %q, but got %q", expected, content)
+	}
+}
+
+func TestMultipartFormBody(t *testing.T) {
+	f := FormFields{
+		{key: "name", value: "bombardier"},
+		{key: "file", value: "testbody.txt", file: true},
+	}
+	if !f.Multipart() {
+		t.Error("expected form with files to be multipart")
+	}
+	body, contentType, err := f.Body()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMultipartForm(t, body, contentType)
+
+	bsp, contentType, err := f.BodyStreamProducer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		rc, err := bsp()
+		if err != nil {
+			t.Fatal(err)
+		}
+		streamed, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkMultipartForm(t, string(streamed), contentType)
+	}
+	// Abandoned bodies must not block
+	rc, err := bsp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+}
+
+func TestFormBodyMissingFile(t *testing.T) {
+	f := FormFields{{key: "file", value: "nosuchfile.txt", file: true}}
+	if _, _, err := f.Body(); err == nil {
+		t.Error("expected missing file to be reported")
+	}
+	if _, _, err := f.BodyStreamProducer(); err == nil {
+		t.Error("expected missing file to be reported")
+	}
+}
@@ -8,10 +8,12 @@ require (
 	github.com/alecthomas/kingpin v2.2.3+incompatible
 	github.com/alecthomas/template v0.0.0-20160405071501-a0175ee3bccc // indirect
 	github.com/alecthomas/units v0.0.0-20151022065526-2efee857e7cf // indirect
+	github.com/andybalholm/brotli v1.1.0
 	github.com/cheggaaa/pb v1.0.7
 	github.com/codesenberg/bombardier v1.2.5
 	github.com/codesenberg/concurrent v0.0.0-20180314162318-11aa0abba0a2
 	github.com/juju/ratelimit v0.0.0-20151125201925-77ed1c8a0121
+	github.com/klauspost/compress v1.11.13
 	github.com/klauspost/crc32 v0.0.0-20161016154125-cb6bfca970f6 // indirect
 	github.com/mattn/go-runewidth v0.0.3-0.20170201023540-14207d285c6c // indirect
 	github.com/satori/go.uuid v1.2.1-0.20180103174451-36e9d2ebbde5
@@ -29,3 +29,13 @@ func (h *HeadersList) Set(value string) error {
 	})
 	return nil
 }
+
+// Has tells if the list contains header with the given name.
+func (h *HeadersList) Has(key string) bool {
+	for _, header := range *h {
+		if strings.EqualFold(header.key, key) {
+			return true
+		}
+	}
+	return false
+}
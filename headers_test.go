@@ -60,3 +60,15 @@ func TestShouldTrimHeaderValues(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestHeadersListHas(t *testing.T) {
+	h := HeadersList{{"Content-Type", "text/plain"}, {"X-Key", "value"}}
+	for _, key := range []string{"Content-Type", "content-type", "X-KEY"} {
+		if !h.Has(key) {
+			t.Errorf("expected %v to be found", key)
+		}
+	}
+	if h.Has("Accept") {
+		t.Error("expected Accept to be missing")
+	}
+}
@@ -0,0 +1,92 @@
+package bombardier
+
+import (
+	"crypto/tls"
+	"net"
+	"sync/atomic"
+)
+
+// ClientIdentities hands out client certificates to new connections
+// in round-robin and counts requests sent using each of them. Each
+// connection is bound to its identity, which its local address then
+// carries, so that clients can tell what identity a request was sent
+// with from the local address of its connection alone.
+type ClientIdentities struct {
+	names  []string
+	certs  []tls.Certificate
+	counts []uint64
+
+	next uint64
+}
+
+func NewClientIdentities(
+	names []string, certs []tls.Certificate,
+) *ClientIdentities {
+	return &ClientIdentities{
+		names:  names,
+		certs:  certs,
+		counts: make([]uint64, len(certs)),
+	}
+}
+
+// Next returns index of the identity the next connection should use.
+func (c *ClientIdentities) Next() int {
+	return int((atomic.AddUint64(&c.next, 1) - 1) % uint64(len(c.certs)))
+}
+
+// Certificate returns certificate of the i-th identity.
+func (c *ClientIdentities) Certificate(i int) tls.Certificate {
+	return c.certs[i]
+}
+
+// identityAddr is local address of connection using the identity-th
+// identity.
+type identityAddr struct {
+	net.Addr
+	identity int
+}
+
+type identityConn struct {
+	net.Conn
+	localAddr *identityAddr
+}
+
+func (c *identityConn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+// Bind returns conn, requests sent over which are counted towards the
+// i-th identity. conn must be bound before TLS handshake, so that TLS
+// connection reports the tagged local address.
+func (c *ClientIdentities) Bind(conn net.Conn, i int) net.Conn {
+	return &identityConn{
+		Conn:      conn,
+		localAddr: &identityAddr{Addr: conn.LocalAddr(), identity: i},
+	}
+}
+
+// Add counts request sent over connection with localAddr, requests
+// sent over connections that weren't bound aren't counted.
+func (c *ClientIdentities) Add(localAddr net.Addr) {
+	if a, ok := localAddr.(*identityAddr); ok {
+		atomic.AddUint64(&c.counts[a.identity], 1)
+	}
+}
+
+type IdentityWithCount struct {
+	identity string
+	count    uint64
+}
+
+// ByIdentity returns number of requests sent using each identity in
+// the order identities were provided.
+func (c *ClientIdentities) ByIdentity() []IdentityWithCount {
+	res := make([]IdentityWithCount, 0, len(c.names))
+	for i, name := range c.names {
+		res = append(res, IdentityWithCount{
+			identity: name,
+			count:    atomic.LoadUint64(&c.counts[i]),
+		})
+	}
+	return res
+}
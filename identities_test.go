@@ -0,0 +1,58 @@
+package bombardier
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestClientIdentities(t *testing.T) {
+	ci := NewClientIdentities(
+		[]string{"a", "b", "c"}, make([]tls.Certificate, 3),
+	)
+	for _, exp := range []int{0, 1, 2, 0} {
+		if i := ci.Next(); i != exp {
+			t.Errorf("expected identity %v, but got %v", exp, i)
+		}
+	}
+	dir, err := ioutil.TempDir("", "bombardier-identities")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// Connections over Unix socket share the same local address
+	l, err := net.Listen("unix", filepath.Join(dir, "test.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	var conns []net.Conn
+	for i := 0; i < 3; i++ {
+		conn, err := net.Dial("unix", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+	first := ci.Bind(conns[0], 0)
+	// Bound connections are to be wrapped with TLS
+	second := tls.Client(ci.Bind(conns[1], 2), &tls.Config{})
+	if first.LocalAddr().String() != second.LocalAddr().String() {
+		t.Errorf("expected the same local addresses, but got %v and %v",
+			first.LocalAddr(), second.LocalAddr())
+	}
+	ci.Add(first.LocalAddr())
+	ci.Add(second.LocalAddr())
+	ci.Add(second.LocalAddr())
+	ci.Add(conns[2].LocalAddr())
+	ci.Add(nil)
+	exp := []IdentityWithCount{{"a", 1}, {"b", 0}, {"c", 2}}
+	if act := ci.ByIdentity(); !reflect.DeepEqual(act, exp) {
+		t.Errorf("expected %v, but got %v", exp, act)
+	}
+}
@@ -26,17 +26,69 @@ type Spec struct {
 	NumberOfRequests uint64
 	TestDuration     time.Duration
 
-	Method string
-	URL    string
+	Method     string
+	URL        string
+	UnixSocket string
+
+	ConnectTo   []string
+	Resolve     []string
+	SpreadAddrs bool
+	LocalAddrs  []string
+	IPVersion   int
+	Proxy       string
 
 	Headers []Header
 
 	Body         string
 	BodyFilePath string
+	Form         []string
 
 	CertPath string
 	KeyPath  string
 
+	CACertPath    string
+	SNI           string
+	TLSMinVersion string
+	TLSMaxVersion string
+	Ciphers       string
+	Curves        string
+	ALPN          string
+	TLSResumption string
+	TLSKeyLog     string
+
+	Auth           string
+	OAuth2TokenURL string
+	OAuth2Scope    string
+
+	Cookies string
+
+	ThinkTime string
+	Pacing    time.Duration
+
+	Pipeline uint64
+
+	MaxConnRequests uint64
+	MaxConnAge      time.Duration
+
+	StreamsPerConnection uint64
+
+	BandwidthLimit string
+	ConnLatency    time.Duration
+
+	SampleResponses uint64
+	SampleDir       string
+
+	TraceLog       string
+	ErrorHistogram bool
+
+	StatusLatencies bool
+
+	CompressBody   string
+	AcceptEncoding string
+	Decompress     bool
+
+	Scenario string
+
 	Stream     bool
 	Timeout    time.Duration
 	ClientType ClientType
@@ -81,10 +133,203 @@ type Results struct {
 	Req1XX, Req2XX, Req3XX, Req4XX, Req5XX uint64
 	Others                                 uint64
 
+	StatusCodes []StatusCodeCount
+
 	Errors []ErrorWithCount
 
 	Latencies ReadonlyUint64Histogram
 	Requests  ReadonlyFloat64Histogram
+
+	// Latencies split by outcome of the request: successful(2xx and
+	// 3xx), 4xx and 5xx responses and requests that failed without one
+	SuccessLatencies ReadonlyUint64Histogram
+	Latencies4XX     ReadonlyUint64Histogram
+	Latencies5XX     ReadonlyUint64Histogram
+	ErrorLatencies   ReadonlyUint64Histogram
+
+	Addresses []AddressStats
+
+	ProxyLatencies ReadonlyUint64Histogram
+
+	TLS           []TLSParamsWithCount
+	TLSHandshakes *TLSHandshakes
+
+	ClientIdentities []IdentityWithCount
+
+	Scenario *ScenarioStats
+
+	Decompression *Decompression
+
+	Pipeline *Pipeline
+
+	Connections *Connections
+
+	Streams *Streams
+
+	Samples    []SampleGroup
+	SamplesDir string
+
+	TraceLog *TraceLog
+}
+
+// TraceLog contains number of requests written to the trace log and
+// dropped from it.
+type TraceLog struct {
+	Written, Dropped uint64
+}
+
+// SampleGroup contains responses with the same status code or
+// occurrences of the same error that were sampled alongside with the
+// number of them seen.
+type SampleGroup struct {
+	Status  int
+	Error   string
+	Seen    uint64
+	Samples []ResponseSample
+}
+
+// ResponseSample is a sampled response or error. Body is truncated, if
+// Truncated is set.
+type ResponseSample struct {
+	Status    int
+	Error     string
+	Headers   []Header
+	Body      string
+	Truncated bool
+}
+
+// Streams contains average and maximum number of HTTP/2 streams in
+// flight per connection.
+type Streams struct {
+	AvgInFlight float64
+	MaxInFlight uint64
+}
+
+// Connections contains number of connections opened by the clients,
+// connections closed by the server and requests sent over already used
+// connections.
+type Connections struct {
+	Opened, ClosedByServer, Reused uint64
+}
+
+// Pipeline contains average and maximum number of requests in flight
+// per pipelined connection alongside with number of requests that
+// overflowed the pipeline or were lost with stopped connections.
+type Pipeline struct {
+	AvgDepth           float64
+	MaxDepth           uint64
+	Overflows, Stopped uint64
+}
+
+// Decompression contains number of compressed bytes received, what they
+// were decoded to and number of bodies that failed to decode.
+type Decompression struct {
+	Compressed, Decompressed uint64
+	Errors                   uint64
+}
+
+// ScenarioStats contains number of scenario iterations that were
+// completed or aborted, time it took to complete them and statistics
+// of every step.
+type ScenarioStats struct {
+	Completed, Aborted uint64
+
+	Latencies ReadonlyUint64Histogram
+
+	Steps []StepStats
+}
+
+// LatenciesStats performs various statistical calculations on
+// time taken by completed iterations.
+func (s ScenarioStats) LatenciesStats(percentiles []float64) *LatenciesStats {
+	return CalculateLatenciesStats(s.Latencies, percentiles)
+}
+
+// StepStats contains number of requests sent by a single step of the
+// scenario, how many of them failed and their latencies.
+type StepStats struct {
+	Name          string
+	Count, Errors uint64
+
+	Latencies ReadonlyUint64Histogram
+}
+
+// LatenciesStats performs various statistical calculations on
+// latencies of requests sent by this step.
+func (s StepStats) LatenciesStats(percentiles []float64) *LatenciesStats {
+	return CalculateLatenciesStats(s.Latencies, percentiles)
+}
+
+// IdentityWithCount contains client certificate alongside with number
+// of requests sent using it.
+type IdentityWithCount struct {
+	Identity string
+	Count    uint64
+}
+
+// TLSHandshakes contains number of full and resumed TLS handshakes
+// alongside with time it took to perform them.
+type TLSHandshakes struct {
+	Full, Resumed uint64
+
+	FullLatencies    ReadonlyUint64Histogram
+	ResumedLatencies ReadonlyUint64Histogram
+}
+
+// FullLatenciesStats performs various statistical calculations on
+// time taken by full handshakes.
+func (t TLSHandshakes) FullLatenciesStats(
+	percentiles []float64,
+) *LatenciesStats {
+	return CalculateLatenciesStats(t.FullLatencies, percentiles)
+}
+
+// ResumedLatenciesStats performs various statistical calculations on
+// time taken by abbreviated handshakes.
+func (t TLSHandshakes) ResumedLatenciesStats(
+	percentiles []float64,
+) *LatenciesStats {
+	return CalculateLatenciesStats(t.ResumedLatencies, percentiles)
+}
+
+// TLSParamsWithCount contains negotiated TLS version and cipher suite
+// alongside with number of connections they were used for.
+type TLSParamsWithCount struct {
+	Version     string
+	CipherSuite string
+	Count       uint64
+}
+
+// AddressStats contains number of requests sent to a single remote
+// address and their latencies.
+type AddressStats struct {
+	Address string
+	Count   uint64
+
+	Latencies ReadonlyUint64Histogram
+}
+
+// LatenciesStats performs various statistical calculations on
+// latencies of requests sent to this address.
+func (a AddressStats) LatenciesStats(percentiles []float64) *LatenciesStats {
+	return CalculateLatenciesStats(a.Latencies, percentiles)
+}
+
+// StatusCodeCount contains number of responses with the status code
+// and, if they were kept, their latencies.
+type StatusCodeCount struct {
+	Code  int
+	Count uint64
+
+	Latencies ReadonlyUint64Histogram
+}
+
+// LatenciesStats performs various statistical calculations on
+// latencies of responses with this status code.
+func (s StatusCodeCount) LatenciesStats(
+	percentiles []float64,
+) *LatenciesStats {
+	return CalculateLatenciesStats(s.Latencies, percentiles)
 }
 
 // ReadonlyUint64Histogram is a readonly histogram with uint64 keys
@@ -121,7 +366,65 @@ type LatenciesStats struct {
 // LatenciesStats performs various statistical calculations on
 // latencies.
 func (r Results) LatenciesStats(percentiles []float64) *LatenciesStats {
-	h := r.Latencies
+	return CalculateLatenciesStats(r.Latencies, percentiles)
+}
+
+// SuccessLatenciesStats performs various statistical calculations on
+// latencies of successful requests.
+func (r Results) SuccessLatenciesStats(
+	percentiles []float64,
+) *LatenciesStats {
+	return CalculateLatenciesStats(r.SuccessLatencies, percentiles)
+}
+
+// OutcomeLatencies contains latencies of requests with the same
+// outcome.
+type OutcomeLatencies struct {
+	Outcome   string
+	Latencies ReadonlyUint64Histogram
+}
+
+// LatenciesStats performs various statistical calculations on
+// latencies of requests with this outcome.
+func (o OutcomeLatencies) LatenciesStats(
+	percentiles []float64,
+) *LatenciesStats {
+	return CalculateLatenciesStats(o.Latencies, percentiles)
+}
+
+// FailedLatencies returns latencies of 4xx and 5xx responses and of
+// requests that failed without one, outcomes that didn't occur are
+// left out.
+func (r Results) FailedLatencies() []OutcomeLatencies {
+	var failed []OutcomeLatencies
+	for _, o := range []OutcomeLatencies{
+		{"4xx", r.Latencies4XX},
+		{"5xx", r.Latencies5XX},
+		{"errors", r.ErrorLatencies},
+	} {
+		if o.Latencies != nil && o.Latencies.Count() > 0 {
+			failed = append(failed, o)
+		}
+	}
+	return failed
+}
+
+// ProxyLatenciesStats performs various statistical calculations on
+// time taken to establish connections through the proxy.
+func (r Results) ProxyLatenciesStats(
+	percentiles []float64,
+) *LatenciesStats {
+	return CalculateLatenciesStats(r.ProxyLatencies, percentiles)
+}
+
+// CalculateLatenciesStats performs various statistical calculations
+// on latencies stored in h.
+func CalculateLatenciesStats(
+	h ReadonlyUint64Histogram, percentiles []float64,
+) *LatenciesStats {
+	if h == nil {
+		return nil
+	}
 	sum := uint64(0)
 	count := uint64(0)
 	max := uint64(0)
@@ -275,11 +578,60 @@ func (r Results) RequestsStats(percentiles []float64) *RequestsStats {
 	}
 }
 
-// ErrorWithCount contains error description alongside with number of
-// times this error occurred.
+// ErrorWithCount contains class of the errors and description of one
+// of them alongside with number of times errors of the class occurred.
+// Errors that weren't classified are told apart by their descriptions.
+// First and Last are times since the start of the test errors were
+// first and last seen at, PerSecond, if errors were counted per second,
+// contains number of them during each second of the test.
 type ErrorWithCount struct {
+	Class ErrorClass
 	Error string
 	Count uint64
+
+	First, Last time.Duration
+	PerSecond   []uint64
+}
+
+// ErrorClass is a category of errors.
+type ErrorClass int
+
+const (
+	// ErrorOther is any error that doesn't fall into other categories.
+	ErrorOther ErrorClass = iota
+	// ErrorTimeout is a request that timed out.
+	ErrorTimeout
+	// ErrorRefused is a connection refused by the server.
+	ErrorRefused
+	// ErrorReset is a connection reset or closed by the server.
+	ErrorReset
+	// ErrorTLS is a failed TLS handshake.
+	ErrorTLS
+	// ErrorDNS is a host that failed to be resolved.
+	ErrorDNS
+	// ErrorBodyRead is a response body that failed to be read or
+	// decoded.
+	ErrorBodyRead
+	// ErrorAssertion is a response that didn't meet expectations.
+	ErrorAssertion
+)
+
+var errorClassNames = [...]string{
+	ErrorOther:     "other",
+	ErrorTimeout:   "timeout",
+	ErrorRefused:   "connection refused",
+	ErrorReset:     "connection reset",
+	ErrorTLS:       "tls",
+	ErrorDNS:       "dns",
+	ErrorBodyRead:  "body read",
+	ErrorAssertion: "assertion",
+}
+
+func (c ErrorClass) String() string {
+	if c < 0 || int(c) >= len(errorClassNames) {
+		return "unknown"
+	}
+	return errorClassNames[c]
 }
 
 // TestType represents the type of test that were performed.
@@ -0,0 +1,156 @@
+package bombardier
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Failed refreshes are retried with this interval until the token
+	// is obtained or the test is over.
+	oauth2RetryInterval = time.Second
+
+	maxOAuth2ResponseSize = 1 << 20
+)
+
+// OAuth2Authorizer obtains access tokens using OAuth 2.0 client
+// credentials grant and adds them to every request. Tokens are
+// refreshed in background after 80% of their lifetime passed.
+type OAuth2Authorizer struct {
+	tokenURL               string
+	clientID, clientSecret string
+	scope                  string
+	client                 *http.Client
+	errors                 *ErrorMap
+	headers                atomic.Value // []Header
+	expiresIn              atomic.Value // time.Duration
+	retryInterval          time.Duration
+}
+
+// NewOAuth2Authorizer returns authorizer obtaining tokens with the
+// same CA certificates, client certificates and TLS parameters as the
+// requests of the test, as set in tlsConfig.
+func NewOAuth2Authorizer(
+	c Config, tlsConfig *tls.Config, errors *ErrorMap,
+) *OAuth2Authorizer {
+	var tokenTLSConfig *tls.Config
+	if tlsConfig != nil {
+		tokenTLSConfig = tlsConfig.Clone()
+		// SNI and protocols are those of the tested server, while token
+		// endpoint is verified against its own name and queried over
+		// HTTP/1.1. Its sessions aren't mixed with those of the test
+		// either.
+		tokenTLSConfig.ServerName = ""
+		tokenTLSConfig.NextProtos = nil
+		tokenTLSConfig.ClientSessionCache = NewClientSessionCache(
+			c.tlsResumption, 1,
+		)
+	}
+	tr := &http.Transport{TLSClientConfig: tokenTLSConfig}
+	return &OAuth2Authorizer{
+		tokenURL:      c.oauth2TokenURL,
+		clientID:      c.clientID,
+		clientSecret:  c.clientSecret,
+		scope:         c.scope,
+		client:        &http.Client{Transport: tr, Timeout: c.timeout},
+		errors:        errors,
+		retryInterval: oauth2RetryInterval,
+	}
+}
+
+type oauth2Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Refresh requests new token from the token endpoint.
+func (o *OAuth2Authorizer) Refresh() error {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if o.scope != "" {
+		form.Set("scope", o.scope)
+	}
+	req, err := http.NewRequest(
+		"POST", o.tokenURL, strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return fmt.Errorf("oauth2: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(o.clientID),
+		url.QueryEscape(o.clientSecret))
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("oauth2: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxOAuth2ResponseSize))
+	if err != nil {
+		return fmt.Errorf("oauth2: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oauth2: token request failed: %v", resp.Status)
+	}
+	var token oauth2Token
+	if err = json.Unmarshal(body, &token); err != nil {
+		return fmt.Errorf("oauth2: invalid token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return fmt.Errorf("oauth2: no access token in response")
+	}
+	tokenType := token.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	o.headers.Store([]Header{
+		{"Authorization", tokenType + " " + token.AccessToken},
+	})
+	o.expiresIn.Store(time.Duration(token.ExpiresIn) * time.Second)
+	return nil
+}
+
+// RefreshInBackground keeps the token fresh until done is closed.
+// Failed attempts are recorded as errors and retried.
+func (o *OAuth2Authorizer) RefreshInBackground(done <-chan struct{}) {
+	timer := time.NewTimer(o.refreshIn())
+	defer timer.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-timer.C:
+		}
+		wait := o.retryInterval
+		if err := o.Refresh(); err != nil {
+			o.errors.Add(err)
+		} else {
+			wait = o.refreshIn()
+		}
+		timer.Reset(wait)
+	}
+}
+
+// refreshIn tells how long to wait before refreshing the token, tokens
+// that don't expire are never refreshed.
+func (o *OAuth2Authorizer) refreshIn() time.Duration {
+	expiresIn, _ := o.expiresIn.Load().(time.Duration)
+	if expiresIn <= 0 {
+		return time.Duration(1<<63 - 1)
+	}
+	return expiresIn * 4 / 5
+}
+
+func (o *OAuth2Authorizer) Authorize(
+	method, host string, u *url.URL, body *string,
+) ([]Header, error) {
+	return o.headers.Load().([]Header), nil
+}
@@ -0,0 +1,236 @@
+package bombardier
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gho1b/bombardier/internal"
+)
+
+// newTestTokenServer starts OAuth2 token endpoint stand-in, that issues
+// tokens "token-1", "token-2", ... and fails requests after the
+// failAfter-th one (if it's non-zero).
+func newTestTokenServer(
+	t *testing.T, expiresIn int, failAfter uint64,
+) *httptest.Server {
+	var issued uint64
+	return httptest.NewServer(http.HandlerFunc(
+		func(rw http.ResponseWriter, r *http.Request) {
+			id, secret, _ := r.BasicAuth()
+			if r.Method != "POST" || id != "client" || secret != "secret" ||
+				r.FormValue("grant_type") != "client_credentials" ||
+				r.FormValue("scope") != "read write" {
+				t.Errorf("unexpected token request: %v %v %v",
+					r.Method, id, r.Form)
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			n := atomic.AddUint64(&issued, 1)
+			if failAfter != 0 && n > failAfter {
+				rw.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			rw.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(rw,
+				`{"access_token":"token-%v","token_type":"bearer",`+
+					`"expires_in":%v}`, n, expiresIn)
+		},
+	))
+}
+
+func newTestOAuth2Authorizer(tokenURL string) *OAuth2Authorizer {
+	return NewOAuth2Authorizer(Config{
+		oauth2TokenURL: tokenURL,
+		clientID:       "client",
+		clientSecret:   "secret",
+		scope:          "read write",
+		timeout:        defaultTimeout,
+	}, nil, NewErrorMap())
+}
+
+func TestOAuth2AuthorizerRefreshesToken(t *testing.T) {
+	s := newTestTokenServer(t, 1, 0)
+	defer s.Close()
+	o := newTestOAuth2Authorizer(s.URL)
+	if err := o.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	headers, _ := o.Authorize("GET", "localhost", nil, nil)
+	exp := []Header{{"Authorization", "Bearer token-1"}}
+	if !reflect.DeepEqual(headers, exp) {
+		t.Errorf("expected %v, but got %v", exp, headers)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go o.RefreshInBackground(done)
+	exp = []Header{{"Authorization", "Bearer token-2"}}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		headers, _ = o.Authorize("GET", "localhost", nil, nil)
+		if reflect.DeepEqual(headers, exp) {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Errorf("token wasn't refreshed, got %v", headers)
+}
+
+func TestOAuth2AuthorizerRecordsErrors(t *testing.T) {
+	s := newTestTokenServer(t, 1, 1)
+	defer s.Close()
+	o := newTestOAuth2Authorizer(s.URL)
+	o.retryInterval = 50 * time.Millisecond
+	if err := o.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	done, finished := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(finished)
+		o.RefreshInBackground(done)
+	}()
+	time.Sleep(1200 * time.Millisecond)
+	close(done)
+	<-finished
+	errs := o.errors.ByFrequency()
+	if len(errs) != 1 || errs[0].count < 2 {
+		t.Fatalf("expected repeated refresh failures, but got %v", errs)
+	}
+	// The last token obtained is used until the refresh succeeds
+	headers, _ := o.Authorize("GET", "localhost", nil, nil)
+	exp := []Header{{"Authorization", "Bearer token-1"}}
+	if !reflect.DeepEqual(headers, exp) {
+		t.Errorf("expected %v, but got %v", exp, headers)
+	}
+}
+
+func TestOAuth2AuthorizerUsesTLSConfig(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(
+		func(rw http.ResponseWriter, r *http.Request) {
+			rw.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(rw, `{"access_token":"token","token_type":"bearer"}`)
+		},
+	))
+	defer s.Close()
+	c := Config{
+		oauth2TokenURL: s.URL,
+		clientID:       "client",
+		timeout:        defaultTimeout,
+	}
+	if err := NewOAuth2Authorizer(c, nil, NewErrorMap()).Refresh(); err == nil {
+		t.Fatal("expected token server certificate to be rejected")
+	}
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(s.Certificate())
+	// SNI and session cache of the test don't apply to token endpoint
+	tlsConfig := &tls.Config{
+		RootCAs:            rootCAs,
+		ServerName:         "target.test",
+		NextProtos:         []string{"h2"},
+		ClientSessionCache: tls.NewLRUClientSessionCache(0),
+	}
+	c.tlsResumption = tlsResumptionCache
+	o := NewOAuth2Authorizer(c, tlsConfig, NewErrorMap())
+	if err := o.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	headers, _ := o.Authorize("GET", "localhost", nil, nil)
+	exp := []Header{{"Authorization", "Bearer token"}}
+	if !reflect.DeepEqual(headers, exp) {
+		t.Errorf("expected %v, but got %v", exp, headers)
+	}
+	if len(tlsConfig.NextProtos) != 1 || tlsConfig.ServerName == "" {
+		t.Errorf("TLS config of the test was modified: %+v", tlsConfig)
+	}
+	tokenTLSConfig := o.client.Transport.(*http.Transport).TLSClientConfig
+	if tokenTLSConfig.ClientSessionCache == nil ||
+		tokenTLSConfig.ClientSessionCache == tlsConfig.ClientSessionCache {
+		t.Error("expected token client to have a session cache of its own")
+	}
+}
+
+func TestOAuth2AuthorizerKeepsErrorCause(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+	o := newTestOAuth2Authorizer("http://" + addr + "/token")
+	err = o.Refresh()
+	if class := ClassifyError(err); class != internal.ErrorRefused {
+		t.Errorf("expected %v to be classified as refused, but got %v",
+			err, class)
+	}
+}
+
+func TestBombardierUsesOAuth2Token(t *testing.T) {
+	testAllClients(t, testBombardierUsesOAuth2Token)
+}
+
+func testBombardierUsesOAuth2Token(clientType ClientTyp, t *testing.T) {
+	tokenServer := newTestTokenServer(t, 3600, 0)
+	defer tokenServer.Close()
+	var unauthorized uint64
+	s := httptest.NewServer(http.HandlerFunc(
+		func(rw http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token-1" {
+				atomic.AddUint64(&unauthorized, 1)
+				rw.WriteHeader(http.StatusUnauthorized)
+			}
+		},
+	))
+	defer s.Close()
+	numReqs := uint64(10)
+	b, e := NewBombardier(Config{
+		numConns:       defaultNumberOfConns,
+		numReqs:        &numReqs,
+		url:            s.URL,
+		headers:        new(HeadersList),
+		timeout:        defaultTimeout,
+		method:         "GET",
+		oauth2TokenURL: tokenServer.URL,
+		clientID:       "client",
+		clientSecret:   "secret",
+		scope:          "read write",
+		clientType:     clientType,
+		format:         KnownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.DisableOutput()
+	b.Bombard()
+	if b.req2xx != numReqs || unauthorized != 0 {
+		t.Errorf("expected %v authorized requests, but got %v",
+			numReqs, b.req2xx)
+	}
+}
+
+func TestBombardierFailsWithoutOAuth2Token(t *testing.T) {
+	tokenServer := newTestTokenServer(t, 3600, 0)
+	tokenServer.Close()
+	numReqs := uint64(10)
+	_, e := NewBombardier(Config{
+		numConns:       defaultNumberOfConns,
+		numReqs:        &numReqs,
+		url:            "http://localhost",
+		headers:        new(HeadersList),
+		timeout:        defaultTimeout,
+		method:         "GET",
+		oauth2TokenURL: tokenServer.URL,
+		clientID:       "client",
+		format:         KnownFormat("plain-text"),
+	})
+	if e == nil {
+		t.Error("expected error when token can't be obtained")
+	}
+}
@@ -0,0 +1,114 @@
+package bombardier
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+	"github.com/valyala/fasthttp"
+)
+
+// fasthttp doesn't export this error, so it can only be recognized by
+// its message.
+const pipelineConnStoppedMsg = "pipeline connection has been stopped"
+
+// PipelineStats keeps track of how many requests were pipelined over
+// each connection and of errors specific to pipelining.
+type PipelineStats struct {
+	inFlight, conns int64
+
+	// In-flight requests per connection sampled as requests are sent
+	depths *uhist.Histogram
+
+	overflows, stopped uint64
+}
+
+func NewPipelineStats() *PipelineStats {
+	return &PipelineStats{depths: uhist.Default()}
+}
+
+// Dial wraps dial, so that open connections are counted.
+func (p *PipelineStats) Dial(
+	dial func(string) (net.Conn, error),
+) func(string) (net.Conn, error) {
+	return func(address string) (net.Conn, error) {
+		conn, err := dial(address)
+		if err != nil {
+			return nil, err
+		}
+		atomic.AddInt64(&p.conns, 1)
+		return &pipelineConn{Conn: conn, conns: &p.conns}, nil
+	}
+}
+
+// Begin records request about to be sent.
+func (p *PipelineStats) Begin() {
+	inFlight := atomic.AddInt64(&p.inFlight, 1)
+	conns := atomic.LoadInt64(&p.conns)
+	if conns < 1 {
+		conns = 1
+	}
+	p.depths.Increment(uint64((inFlight + conns - 1) / conns))
+}
+
+// End records request that was completed with err.
+func (p *PipelineStats) End(err error) {
+	atomic.AddInt64(&p.inFlight, -1)
+	if err == nil {
+		return
+	}
+	if err == fasthttp.ErrPipelineOverflow {
+		atomic.AddUint64(&p.overflows, 1)
+	} else if err.Error() == pipelineConnStoppedMsg {
+		atomic.AddUint64(&p.stopped, 1)
+	}
+}
+
+// Depth returns average and maximum number of requests in flight per
+// connection.
+func (p *PipelineStats) Depth() (avg float64, max uint64) {
+	return avgAndMax(p.depths)
+}
+
+// Overflows returns number of requests rejected, because the queue of
+// pipelined requests was full.
+func (p *PipelineStats) Overflows() uint64 {
+	return atomic.LoadUint64(&p.overflows)
+}
+
+// Stopped returns number of requests failed, because the connection
+// they were pipelined over was stopped.
+func (p *PipelineStats) Stopped() uint64 {
+	return atomic.LoadUint64(&p.stopped)
+}
+
+// avgAndMax returns average and maximum of the values recorded in h.
+func avgAndMax(h *uhist.Histogram) (avg float64, max uint64) {
+	var sum, count uint64
+	h.VisitAll(func(value, n uint64) bool {
+		sum += value * n
+		count += n
+		if value > max {
+			max = value
+		}
+		return true
+	})
+	if count == 0 {
+		return 0, 0
+	}
+	return float64(sum) / float64(count), max
+}
+
+type pipelineConn struct {
+	net.Conn
+	once  sync.Once
+	conns *int64
+}
+
+func (p *pipelineConn) Close() error {
+	p.once.Do(func() {
+		atomic.AddInt64(p.conns, -1)
+	})
+	return p.Conn.Close()
+}
@@ -0,0 +1,71 @@
+package bombardier
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestPipelineStats(t *testing.T) {
+	p := NewPipelineStats()
+	dial := p.Dial(func(string) (net.Conn, error) {
+		c, _ := net.Pipe()
+		return c, nil
+	})
+	conns := make([]net.Conn, 0, 2)
+	for i := 0; i < 2; i++ {
+		conn, err := dial("localhost:8080")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, conn)
+	}
+	// 1, 1, 2, 2 requests per connection
+	for i := 0; i < 4; i++ {
+		p.Begin()
+	}
+	for i := 0; i < 4; i++ {
+		p.End(nil)
+	}
+	if avg, max := p.Depth(); avg != 1.5 || max != 2 {
+		t.Errorf("expected depth of 1.5 avg and 2 max, but got %v and %v",
+			avg, max)
+	}
+	for _, conn := range conns {
+		_ = conn.Close()
+		_ = conn.Close()
+	}
+	if p.conns != 0 {
+		t.Errorf("expected all connections to be closed, but got %v",
+			p.conns)
+	}
+	// Only pipelining errors are counted
+	p.Begin()
+	p.End(fasthttp.ErrPipelineOverflow)
+	p.Begin()
+	p.End(errors.New(pipelineConnStoppedMsg))
+	p.Begin()
+	p.End(errors.New("some other error"))
+	if p.Overflows() != 1 || p.Stopped() != 1 {
+		t.Errorf("expected 1 overflow and 1 stop, but got %v and %v",
+			p.Overflows(), p.Stopped())
+	}
+	if p.inFlight != 0 {
+		t.Errorf("expected no requests in flight, but got %v", p.inFlight)
+	}
+}
+
+func TestPipelineStatsDialError(t *testing.T) {
+	p := NewPipelineStats()
+	dial := p.Dial(func(string) (net.Conn, error) {
+		return nil, errors.New("refused")
+	})
+	if _, err := dial("localhost:8080"); err == nil {
+		t.Error("expected dial error to be returned")
+	}
+	if p.conns != 0 {
+		t.Error("failed dials shouldn't be counted")
+	}
+}
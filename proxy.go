@@ -0,0 +1,259 @@
+package bombardier
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var proxySchemePorts = map[string]string{
+	"http":   "80",
+	"socks5": "1080",
+}
+
+// ProxyAddr returns host:port of the proxy, filling in the default
+// port for the proxy's scheme if necessary.
+func ProxyAddr(proxy *url.URL) string {
+	if proxy.Port() != "" {
+		return proxy.Host
+	}
+	return net.JoinHostPort(proxy.Hostname(), proxySchemePorts[proxy.Scheme])
+}
+
+// ForwardsThroughProxy tells whether requests to target are sent to
+// the proxy as is (with absolute URI), rather than through a tunnel.
+// Only plain HTTP requests to HTTP proxies are forwarded, HTTPS
+// requests use CONNECT and SOCKS5 proxies always tunnel.
+func ForwardsThroughProxy(proxy, target *url.URL) bool {
+	return proxy.Scheme == "http" && target.Scheme == "http"
+}
+
+// ProxyAuthorization returns the value of Proxy-Authorization header
+// for HTTP proxies or an empty string if no credentials were provided.
+func ProxyAuthorization(proxy *url.URL) string {
+	if proxy.User == nil {
+		return ""
+	}
+	password, _ := proxy.User.Password()
+	creds := proxy.User.Username() + ":" + password
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(creds))
+}
+
+// RedactedProxyURL returns proxy URL with password replaced, so it
+// can be safely printed.
+func RedactedProxyURL(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil || u.User == nil {
+		return proxy
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	return u.String()
+}
+
+// ProxyHandshake establishes a tunnel to address through the proxy
+// conn is connected to.
+func ProxyHandshake(
+	conn net.Conn, proxy *url.URL, address string, timeout time.Duration,
+) error {
+	if timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return err
+		}
+		defer conn.SetDeadline(time.Time{})
+	}
+	switch proxy.Scheme {
+	case "http":
+		return httpConnect(conn, proxy, address)
+	case "socks5":
+		return socks5Connect(conn, proxy, address)
+	}
+	return fmt.Errorf("unsupported proxy scheme %q", proxy.Scheme)
+}
+
+const maxProxyResponseHeaderSize = 16 << 10
+
+func httpConnect(conn net.Conn, proxy *url.URL, address string) error {
+	req := "CONNECT " + address + " HTTP/1.1\r\nHost: " + address + "\r\n"
+	if auth := ProxyAuthorization(proxy); auth != "" {
+		req += "Proxy-Authorization: " + auth + "\r\n"
+	}
+	req += "\r\n"
+	if _, err := io.WriteString(conn, req); err != nil {
+		return err
+	}
+
+	// Read the response byte by byte, so that nothing that follows
+	// the header is consumed from the connection.
+	head := make([]byte, 0, 512)
+	b := make([]byte, 1)
+	for !bytes.HasSuffix(head, []byte("\r\n\r\n")) {
+		if len(head) >= maxProxyResponseHeaderSize {
+			return fmt.Errorf("proxy CONNECT: response header is too large")
+		}
+		if _, err := io.ReadFull(conn, b); err != nil {
+			return fmt.Errorf("proxy CONNECT: %w", err)
+		}
+		head = append(head, b[0])
+	}
+	resp, err := http.ReadResponse(
+		bufio.NewReader(bytes.NewReader(head)), nil,
+	)
+	if err != nil {
+		return fmt.Errorf("proxy CONNECT: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy CONNECT: %v", resp.Status)
+	}
+	return nil
+}
+
+const (
+	socks5Version = 0x05
+
+	socks5NoAuth       = 0x00
+	socks5PasswordAuth = 0x02
+	socks5NoAcceptable = 0xff
+
+	socks5CmdConnect = 0x01
+
+	socks5IPv4   = 0x01
+	socks5Domain = 0x03
+	socks5IPv6   = 0x04
+)
+
+var socks5Replies = []string{
+	"succeeded",
+	"general SOCKS server failure",
+	"connection not allowed by ruleset",
+	"network unreachable",
+	"host unreachable",
+	"connection refused",
+	"TTL expired",
+	"command not supported",
+	"address type not supported",
+}
+
+func socks5Connect(conn net.Conn, proxy *url.URL, address string) error {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return err
+	}
+
+	methods := []byte{socks5NoAuth}
+	if proxy.User != nil {
+		methods = append(methods, socks5PasswordAuth)
+	}
+	greeting := append([]byte{socks5Version, byte(len(methods))}, methods...)
+	if _, err = conn.Write(greeting); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err = io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("socks5: %w", err)
+	}
+	if reply[0] != socks5Version {
+		return fmt.Errorf("socks5: unexpected protocol version %v", reply[0])
+	}
+	switch reply[1] {
+	case socks5NoAuth:
+	case socks5PasswordAuth:
+		if proxy.User == nil {
+			return fmt.Errorf("socks5: proxy requires authentication")
+		}
+		if err = socks5Authenticate(conn, proxy.User); err != nil {
+			return err
+		}
+	case socks5NoAcceptable:
+		return fmt.Errorf("socks5: no acceptable authentication methods")
+	default:
+		return fmt.Errorf("socks5: unsupported authentication method %v",
+			reply[1])
+	}
+
+	req := []byte{socks5Version, socks5CmdConnect, 0}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return fmt.Errorf("socks5: host name is too long: %v", host)
+		}
+		req = append(req, socks5Domain, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, socks5IPv4)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, socks5IPv6)
+		req = append(req, ip.To16()...)
+	}
+	req = append(req, 0, 0)
+	binary.BigEndian.PutUint16(req[len(req)-2:], uint16(port))
+	if _, err = conn.Write(req); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	if _, err = io.ReadFull(conn, header); err != nil {
+		return fmt.Errorf("socks5: %w", err)
+	}
+	if rep := int(header[1]); rep != 0 {
+		if rep < len(socks5Replies) {
+			return fmt.Errorf("socks5: %v", socks5Replies[rep])
+		}
+		return fmt.Errorf("socks5: unknown reply %v", rep)
+	}
+	// Skip the bound address, it's of no interest to us.
+	var addrLen int
+	switch header[3] {
+	case socks5IPv4:
+		addrLen = net.IPv4len
+	case socks5IPv6:
+		addrLen = net.IPv6len
+	case socks5Domain:
+		if _, err = io.ReadFull(conn, header[:1]); err != nil {
+			return fmt.Errorf("socks5: %w", err)
+		}
+		addrLen = int(header[0])
+	default:
+		return fmt.Errorf("socks5: unknown address type %v", header[3])
+	}
+	if _, err = io.ReadFull(conn, make([]byte, addrLen+2)); err != nil {
+		return fmt.Errorf("socks5: %w", err)
+	}
+	return nil
+}
+
+func socks5Authenticate(conn net.Conn, user *url.Userinfo) error {
+	username := user.Username()
+	password, _ := user.Password()
+	if len(username) > 255 || len(password) > 255 {
+		return fmt.Errorf("socks5: username or password is too long")
+	}
+	req := []byte{0x01, byte(len(username))}
+	req = append(req, username...)
+	req = append(req, byte(len(password)))
+	req = append(req, password...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("socks5: %w", err)
+	}
+	if reply[1] != 0 {
+		return fmt.Errorf("socks5: authentication failed")
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package bombardier
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ConnectToList maps host:port pairs to host:port pairs that should
+// be dialed instead. Either part of both pairs may be empty, which
+// means "any" on the left side and "keep as is" on the right side.
+type ConnectToList map[string]string
+
+func (c *ConnectToList) String() string {
+	return strings.Join(c.Entries(), " ")
+}
+
+func (c *ConnectToList) IsCumulative() bool {
+	return true
+}
+
+func (c *ConnectToList) Set(value string) error {
+	parts := splitAddrList(value)
+	if len(parts) != 4 {
+		return errInvalidConnectToFormat
+	}
+	if *c == nil {
+		*c = make(ConnectToList)
+	}
+	from := net.JoinHostPort(parts[0], parts[1])
+	(*c)[from] = net.JoinHostPort(parts[2], parts[3])
+	return nil
+}
+
+// Entries returns overrides in the HOST1:PORT1:HOST2:PORT2 form
+// sorted alphabetically.
+func (c *ConnectToList) Entries() []string {
+	entries := make([]string, 0, len(*c))
+	for from, to := range *c {
+		entries = append(entries, from+":"+to)
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// Apply returns the address that should be dialed instead of address.
+func (c ConnectToList) Apply(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	for _, from := range []string{
+		net.JoinHostPort(host, port),
+		net.JoinHostPort(host, ""),
+		net.JoinHostPort("", port),
+		net.JoinHostPort("", ""),
+	} {
+		to, ok := c[from]
+		if !ok {
+			continue
+		}
+		toHost, toPort, _ := net.SplitHostPort(to)
+		if toHost == "" {
+			toHost = host
+		}
+		if toPort == "" {
+			toPort = port
+		}
+		return net.JoinHostPort(toHost, toPort)
+	}
+	return address
+}
+
+// ResolveList is a static map from host:port pairs to IP addresses,
+// that is used instead of the system resolver.
+type ResolveList map[string][]string
+
+func (r *ResolveList) String() string {
+	return strings.Join(r.Entries(), " ")
+}
+
+func (r *ResolveList) IsCumulative() bool {
+	return true
+}
+
+func (r *ResolveList) Set(value string) error {
+	parts := splitAddrList(value)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return errInvalidResolveFormat
+	}
+	var ips []string
+	for _, ip := range strings.Split(parts[2], ",") {
+		ip = strings.TrimSuffix(strings.TrimPrefix(ip, "["), "]")
+		if net.ParseIP(ip) == nil {
+			return errInvalidResolveFormat
+		}
+		ips = append(ips, ip)
+	}
+	if *r == nil {
+		*r = make(ResolveList)
+	}
+	hostPort := net.JoinHostPort(parts[0], parts[1])
+	(*r)[hostPort] = append((*r)[hostPort], ips...)
+	return nil
+}
+
+// Entries returns static entries in the HOST:PORT:ADDR[,ADDR]... form
+// sorted alphabetically.
+func (r *ResolveList) Entries() []string {
+	entries := make([]string, 0, len(*r))
+	for hostPort, ips := range *r {
+		quoted := make([]string, 0, len(ips))
+		for _, ip := range ips {
+			if strings.Contains(ip, ":") {
+				ip = "[" + ip + "]"
+			}
+			quoted = append(quoted, ip)
+		}
+		entries = append(entries, hostPort+":"+strings.Join(quoted, ","))
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// splitAddrList splits colon-separated list, treating colons inside of
+// square brackets (IPv6 addresses) as a part of the element.
+func splitAddrList(s string) []string {
+	var (
+		parts []string
+		depth int
+		start int
+	)
+	for i, r := range s {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ':':
+			if depth == 0 {
+				parts = append(parts, trimBrackets(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, trimBrackets(s[start:]))
+}
+
+func trimBrackets(s string) string {
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// TargetResolver decides which addresses should be dialed to reach
+// the target, taking into account user provided overrides.
+type TargetResolver struct {
+	connectTo ConnectToList
+	resolve   ResolveList
+	spread    bool
+	ipVersion int
+
+	next uint64
+
+	mu    sync.Mutex
+	cache map[string][]string
+}
+
+func NewTargetResolver(opts *ClientOpts) *TargetResolver {
+	r := &TargetResolver{
+		spread:    opts.spreadAddrs,
+		ipVersion: opts.ipVersion,
+		cache:     make(map[string][]string),
+	}
+	if opts.connectTo != nil {
+		r.connectTo = *opts.connectTo
+	}
+	if opts.resolve != nil {
+		r.resolve = *opts.resolve
+	}
+	return r
+}
+
+// Addrs returns the list of addresses to try in order to connect to
+// address. In spread mode each subsequent call starts from the next
+// address, so that new connections are distributed evenly across all
+// addresses the target resolves to.
+func (r *TargetResolver) Addrs(
+	ctx context.Context, address string,
+) ([]string, error) {
+	return r.AddrsOfVersion(ctx, address, r.ipVersion)
+}
+
+// AddrsOfVersion is like Addrs, but only returns addresses of the
+// given IP version, which is used for connections from local addresses
+// of that version.
+func (r *TargetResolver) AddrsOfVersion(
+	ctx context.Context, address string, ipVersion int,
+) ([]string, error) {
+	address = r.connectTo.Apply(address)
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	ips, ok := r.resolve[address]
+	if !ok {
+		if !r.spread {
+			return []string{address}, nil
+		}
+		ips, err = r.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+	}
+	addrs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		if ipVersion != anyIPVersion &&
+			IPVersionOf(net.ParseIP(ip)) != ipVersion {
+			continue
+		}
+		addrs = append(addrs, net.JoinHostPort(ip, port))
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf(
+			"no IPv%v addresses found for %v", ipVersion, host,
+		)
+	}
+	if r.spread && len(addrs) > 1 {
+		i := int((atomic.AddUint64(&r.next, 1) - 1) % uint64(len(addrs)))
+		addrs = append(addrs[i:], addrs[:i]...)
+	}
+	return addrs, nil
+}
+
+// lookup resolves host only once per run, so that the set of addresses
+// connections are spread across stays the same.
+func (r *TargetResolver) lookup(
+	ctx context.Context, host string,
+) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ips, ok := r.cache[host]; ok {
+		return ips, nil
+	}
+	ipAddrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]string, 0, len(ipAddrs))
+	for _, ia := range ipAddrs {
+		ips = append(ips, ia.IP.String())
+	}
+	r.cache[host] = ips
+	return ips, nil
+}
@@ -0,0 +1,192 @@
+package bombardier
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestConnectToListParsing(t *testing.T) {
+	c := new(ConnectToList)
+	for _, v := range []string{
+		"example.com:443:10.0.0.1:8443",
+		"::[::1]:",
+		"[fe80::1]:80::8080",
+	} {
+		if err := c.Set(v); err != nil {
+			t.Error(v, err)
+		}
+	}
+	e := ConnectToList{
+		"example.com:443": "10.0.0.1:8443",
+		":":               "[::1]:",
+		"[fe80::1]:80":    ":8080",
+	}
+	if !reflect.DeepEqual(*c, e) {
+		t.Errorf("Expected %v, but got %v", e, *c)
+	}
+	for _, v := range []string{"", "a:b:c", "a:1:b:2:c"} {
+		if err := c.Set(v); err != errInvalidConnectToFormat {
+			t.Errorf("%q: expected %v, but got %v",
+				v, errInvalidConnectToFormat, err)
+		}
+	}
+}
+
+func TestConnectToListApply(t *testing.T) {
+	c := ConnectToList{
+		"example.com:443": "10.0.0.1:8443",
+		"example.com:":    "10.0.0.2:",
+		":8080":           ":9090",
+	}
+	expectations := []struct {
+		in, out string
+	}{
+		{"example.com:443", "10.0.0.1:8443"},
+		{"example.com:80", "10.0.0.2:80"},
+		{"other.com:8080", "other.com:9090"},
+		{"other.com:80", "other.com:80"},
+	}
+	for _, e := range expectations {
+		if a := c.Apply(e.in); a != e.out {
+			t.Errorf("Expected %q -> %q, but got %q", e.in, e.out, a)
+		}
+	}
+}
+
+func TestResolveListParsing(t *testing.T) {
+	r := new(ResolveList)
+	for _, v := range []string{
+		"example.com:443:10.0.0.1,10.0.0.2",
+		"example.com:80:[::1]",
+	} {
+		if err := r.Set(v); err != nil {
+			t.Error(v, err)
+		}
+	}
+	e := ResolveList{
+		"example.com:443": {"10.0.0.1", "10.0.0.2"},
+		"example.com:80":  {"::1"},
+	}
+	if !reflect.DeepEqual(*r, e) {
+		t.Errorf("Expected %v, but got %v", e, *r)
+	}
+	ee := []string{
+		"example.com:443:10.0.0.1,10.0.0.2",
+		"example.com:80:[::1]",
+	}
+	if a := r.Entries(); !reflect.DeepEqual(a, ee) {
+		t.Errorf("Expected %v, but got %v", ee, a)
+	}
+	for _, v := range []string{
+		"example.com:443", ":443:10.0.0.1", "example.com:443:notanip",
+	} {
+		if err := r.Set(v); err != errInvalidResolveFormat {
+			t.Errorf("%q: expected %v, but got %v",
+				v, errInvalidResolveFormat, err)
+		}
+	}
+}
+
+func TestTargetResolverAddrs(t *testing.T) {
+	resolve := ResolveList{
+		"example.com:443": {"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+	}
+	connectTo := ConnectToList{
+		"alias.com:": "example.com:",
+	}
+	r := NewTargetResolver(&ClientOpts{
+		resolve:   &resolve,
+		connectTo: &connectTo,
+	})
+	addrs, err := r.Addrs(context.Background(), "alias.com:443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := []string{"10.0.0.1:443", "10.0.0.2:443", "10.0.0.3:443"}
+	if !reflect.DeepEqual(addrs, e) {
+		t.Errorf("Expected %v, but got %v", e, addrs)
+	}
+	addrs, err = r.Addrs(context.Background(), "other.com:80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := []string{"other.com:80"}; !reflect.DeepEqual(addrs, e) {
+		t.Errorf("Expected %v, but got %v", e, addrs)
+	}
+
+	r = NewTargetResolver(&ClientOpts{
+		resolve:     &resolve,
+		spreadAddrs: true,
+	})
+	firsts := make(map[string]int)
+	for i := 0; i < 9; i++ {
+		addrs, err = r.Addrs(context.Background(), "example.com:443")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(addrs) != 3 {
+			t.Fatalf("Expected 3 addresses, but got %v", addrs)
+		}
+		firsts[addrs[0]]++
+	}
+	for _, addr := range e {
+		if firsts[addr] != 3 {
+			t.Errorf("Expected %v to be tried first 3 times, but got %v",
+				addr, firsts[addr])
+		}
+	}
+}
+
+func TestTargetResolverFiltersIPVersion(t *testing.T) {
+	resolve := ResolveList{
+		"example.com:443": {"10.0.0.1", "::1"},
+	}
+	expectations := []struct {
+		ipVersion int
+		addrs     []string
+	}{
+		{anyIPVersion, []string{"10.0.0.1:443", "[::1]:443"}},
+		{ipv4, []string{"10.0.0.1:443"}},
+		{ipv6, []string{"[::1]:443"}},
+	}
+	for _, e := range expectations {
+		r := NewTargetResolver(&ClientOpts{
+			resolve:   &resolve,
+			ipVersion: e.ipVersion,
+		})
+		addrs, err := r.Addrs(context.Background(), "example.com:443")
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !reflect.DeepEqual(addrs, e.addrs) {
+			t.Errorf("Expected %v, but got %v", e.addrs, addrs)
+		}
+	}
+	// Connections from local addresses of a particular version only
+	// try target's addresses of the same version
+	r := NewTargetResolver(&ClientOpts{
+		resolve:     &resolve,
+		spreadAddrs: true,
+	})
+	for i := 0; i < 2; i++ {
+		addrs, err := r.AddrsOfVersion(
+			context.Background(), "example.com:443", ipv6,
+		)
+		if e := []string{"[::1]:443"}; err != nil ||
+			!reflect.DeepEqual(addrs, e) {
+			t.Errorf("Expected %v, but got %v (%v)", e, addrs, err)
+		}
+	}
+	resolve = ResolveList{
+		"example.com:443": {"10.0.0.1"},
+	}
+	r = NewTargetResolver(&ClientOpts{
+		resolve:   &resolve,
+		ipVersion: ipv6,
+	})
+	if _, err := r.Addrs(context.Background(), "example.com:443"); err == nil {
+		t.Error("Expected an error when no addresses match IP version")
+	}
+}
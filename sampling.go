@@ -0,0 +1,226 @@
+package bombardier
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Bodies of sampled responses are truncated to this many bytes.
+const sampleBodyLimit = 4 << 10
+
+// ResponseSample is a response or an error kept for debugging.
+type ResponseSample struct {
+	status    int
+	err       string
+	header    http.Header
+	body      []byte
+	truncated bool
+}
+
+// SampleGroup contains samples of responses with the same status code
+// or of the errors counted together alongside with the number of them
+// seen, err is the message of the first one of them.
+type SampleGroup struct {
+	key     string
+	status  int
+	err     string
+	seen    uint64
+	samples []ResponseSample
+}
+
+// ResponseSampler keeps the first n responses with each status code as
+// well as the first n occurrences of each error and a reservoir sample
+// of n out of the rest of them.
+type ResponseSampler struct {
+	n int
+
+	mu     sync.Mutex
+	rnd    *rand.Rand
+	groups map[string]*SampleGroup
+}
+
+func NewResponseSampler(n uint64) *ResponseSampler {
+	return &ResponseSampler{
+		n:      int(n),
+		rnd:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		groups: make(map[string]*SampleGroup),
+	}
+}
+
+// slot counts response or error with the given key and returns index
+// it has to be stored at or -1 if it isn't sampled.
+func (s *ResponseSampler) slot(key string, status int, err string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.groups[key]
+	if !ok {
+		g = &SampleGroup{key: key, status: status, err: err}
+		s.groups[key] = g
+	}
+	g.seen++
+	if g.seen <= uint64(2*s.n) {
+		// The first n are kept, the rest fill the reservoir up
+		g.samples = append(g.samples, ResponseSample{})
+		return len(g.samples) - 1
+	}
+	// Once the reservoir is full, all of the responses past the first n
+	// are equally likely to be in it
+	if i := s.rnd.Int63n(int64(g.seen) - int64(s.n)); i < int64(s.n) {
+		return s.n + int(i)
+	}
+	return -1
+}
+
+func (s *ResponseSampler) store(key string, slot int, rs ResponseSample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.groups[key].samples[slot] = rs
+}
+
+// Error samples err. Errors are grouped the same way ErrorMap counts
+// them, so that messages of each group may differ, e.g. in addresses.
+func (s *ResponseSampler) Error(err error) {
+	msg := err.Error()
+	key := "error: " + keyOf(err).String()
+	if slot := s.slot(key, 0, msg); slot >= 0 {
+		s.store(key, slot, ResponseSample{err: msg})
+	}
+}
+
+// capture counts response with the given status code and, if it's
+// going to be sampled, returns capture of it alongside with w that
+// also writes the body to the capture.
+func (s *ResponseSampler) capture(
+	status int, w io.Writer,
+) (*capturedResponse, io.Writer) {
+	key := strconv.Itoa(status)
+	slot := s.slot(key, status, "")
+	if slot < 0 {
+		return nil, w
+	}
+	c := &capturedResponse{key: key, slot: slot, status: status}
+	return c, io.MultiWriter(w, &c.body)
+}
+
+// keep stores response that was captured completely.
+func (s *ResponseSampler) keep(c *capturedResponse, header http.Header) {
+	s.store(c.key, c.slot, ResponseSample{
+		status:    c.status,
+		header:    header.Clone(),
+		body:      c.body.buf.Bytes(),
+		truncated: c.body.truncated,
+	})
+}
+
+// Groups returns sampled groups, responses ordered by status code
+// and errors by frequency.
+func (s *ResponseSampler) Groups() []*SampleGroup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	groups := make([]*SampleGroup, 0, len(s.groups))
+	for _, g := range s.groups {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		gi, gj := groups[i], groups[j]
+		if (gi.err == "") != (gj.err == "") {
+			return gi.err == ""
+		}
+		if gi.err == "" {
+			return gi.status < gj.status
+		}
+		if gi.seen != gj.seen {
+			return gi.seen > gj.seen
+		}
+		return gi.err < gj.err
+	})
+	return groups
+}
+
+// WriteTo writes each of the samples to a file of its own in dir.
+func (s *ResponseSampler) WriteTo(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	errorGroups := 0
+	for _, g := range s.Groups() {
+		prefix := "status-" + g.key
+		if g.err != "" {
+			errorGroups++
+			prefix = "error-" + strconv.Itoa(errorGroups)
+		}
+		for i, rs := range g.Samples() {
+			name := fmt.Sprintf("%v-%v.txt", prefix, i+1)
+			err := ioutil.WriteFile(
+				filepath.Join(dir, name), rs.dump(), 0644,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Samples returns samples kept, responses that failed to be read
+// while they were captured are skipped.
+func (g *SampleGroup) Samples() []ResponseSample {
+	samples := make([]ResponseSample, 0, len(g.samples))
+	for _, rs := range g.samples {
+		if rs.status != 0 || rs.err != "" {
+			samples = append(samples, rs)
+		}
+	}
+	return samples
+}
+
+// dump returns the status line, headers and body of the response or
+// the error message.
+func (rs *ResponseSample) dump() []byte {
+	var buf bytes.Buffer
+	if rs.err != "" {
+		fmt.Fprintf(&buf, "Error: %v\n", rs.err)
+		return buf.Bytes()
+	}
+	fmt.Fprintf(&buf, "%v %v\n", rs.status, http.StatusText(rs.status))
+	_ = rs.header.Write(&buf)
+	buf.WriteString("\n")
+	buf.Write(rs.body)
+	if rs.truncated {
+		buf.WriteString("\n[truncated]\n")
+	}
+	return buf.Bytes()
+}
+
+type capturedResponse struct {
+	key    string
+	slot   int
+	status int
+	body   limitedBuffer
+}
+
+// limitedBuffer keeps at most sampleBodyLimit bytes written to it and
+// discards the rest.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if room := sampleBodyLimit - l.buf.Len(); n > room {
+		p, l.truncated = p[:room], true
+	}
+	l.buf.Write(p)
+	return n, nil
+}
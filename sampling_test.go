@@ -0,0 +1,187 @@
+package bombardier
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func sampleResponse(s *ResponseSampler, status int, body string) {
+	captured, w := s.capture(status, ioutil.Discard)
+	_, _ = w.Write([]byte(body))
+	if captured != nil {
+		s.keep(captured, http.Header{"X-Status": {body}})
+	}
+}
+
+func TestResponseSamplerKeepsFirstAndReservoir(t *testing.T) {
+	s := NewResponseSampler(2)
+	for i := 0; i < 100; i++ {
+		sampleResponse(s, 200, "ok")
+	}
+	sampleResponse(s, 404, "missing")
+	groups := s.Groups()
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, but got %v", len(groups))
+	}
+	if g := groups[0]; g.status != 200 || g.seen != 100 ||
+		len(g.Samples()) != 4 {
+		t.Errorf("expected 4 of 100 responses with 200, but got %v of %v "+
+			"with %v", len(g.Samples()), g.seen, g.status)
+	}
+	if g := groups[1]; g.status != 404 || g.seen != 1 ||
+		len(g.Samples()) != 1 {
+		t.Errorf("expected 1 of 1 response with 404, but got %v of %v "+
+			"with %v", len(g.Samples()), g.seen, g.status)
+	}
+	rs := groups[1].Samples()[0]
+	if string(rs.body) != "missing" || rs.header.Get("X-Status") != "missing" {
+		t.Errorf("unexpected sample %+v", rs)
+	}
+}
+
+func TestResponseSamplerReservoirIsUniform(t *testing.T) {
+	// Responses past the first one are equally likely to be kept
+	counts := make(map[string]int)
+	for run := 0; run < 2000; run++ {
+		s := NewResponseSampler(1)
+		for _, body := range []string{"a", "b", "c", "d", "e"} {
+			sampleResponse(s, 200, body)
+		}
+		samples := s.Groups()[0].Samples()
+		if len(samples) != 2 || string(samples[0].body) != "a" {
+			t.Fatalf("expected the first response to be kept, got %+v",
+				samples)
+		}
+		counts[string(samples[1].body)]++
+	}
+	for _, body := range []string{"b", "c", "d", "e"} {
+		if counts[body] < 350 || counts[body] > 650 {
+			t.Errorf("%v kept %v times out of 2000", body, counts[body])
+		}
+	}
+}
+
+func TestResponseSamplerErrors(t *testing.T) {
+	s := NewResponseSampler(1)
+	sampleResponse(s, 500, "oops")
+	for i := 0; i < 3; i++ {
+		s.Error(errors.New("connection refused"))
+	}
+	s.Error(errors.New("timeout"))
+	groups := s.Groups()
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, but got %v", len(groups))
+	}
+	// Responses go first, errors are ordered by frequency
+	if groups[0].status != 500 || groups[1].err != "connection refused" ||
+		groups[2].err != "timeout" {
+		t.Errorf("unexpected order of groups %v, %v and %v",
+			groups[0].key, groups[1].key, groups[2].key)
+	}
+	if groups[1].seen != 3 || len(groups[1].Samples()) != 2 {
+		t.Errorf("expected 2 of 3 errors, but got %v of %v",
+			len(groups[1].Samples()), groups[1].seen)
+	}
+}
+
+func TestResponseSamplerGroupsClassifiedErrors(t *testing.T) {
+	s := NewResponseSampler(1)
+	var messages []string
+	for i := 0; i < 5; i++ {
+		err := &net.OpError{
+			Op:  "dial",
+			Net: "tcp",
+			Source: &net.TCPAddr{
+				IP: net.IPv4(127, 0, 0, 1), Port: 40000 + i,
+			},
+			Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080},
+			Err:  os.NewSyscallError("connect", syscall.ECONNREFUSED),
+		}
+		messages = append(messages, err.Error())
+		s.Error(err)
+	}
+	if messages[0] == messages[1] {
+		t.Fatalf("expected distinct messages, but got %v", messages)
+	}
+	groups := s.Groups()
+	if len(groups) != 1 {
+		t.Fatalf("expected a single group, but got %v", len(groups))
+	}
+	g := groups[0]
+	if g.seen != 5 || g.err != messages[0] {
+		t.Errorf("expected 5 errors like %q, but got %v like %q",
+			messages[0], g.seen, g.err)
+	}
+	// Samples keep their own messages
+	samples := g.Samples()
+	if len(samples) != 2 || samples[0].err != messages[0] {
+		t.Errorf("unexpected samples %v", samples)
+	}
+}
+
+func TestResponseSamplerSkipsFailedCaptures(t *testing.T) {
+	s := NewResponseSampler(1)
+	captured, _ := s.capture(200, ioutil.Discard)
+	if captured == nil {
+		t.Fatal("expected the first response to be captured")
+	}
+	// Body of the response failed to be read, so it isn't kept
+	if samples := s.Groups()[0].Samples(); len(samples) != 0 {
+		t.Errorf("expected no samples, but got %v", len(samples))
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	var l limitedBuffer
+	chunk := bytes.Repeat([]byte("a"), sampleBodyLimit/2+1)
+	for i := 0; i < 3; i++ {
+		if n, err := l.Write(chunk); n != len(chunk) || err != nil {
+			t.Fatalf("expected whole chunk to be written, got %v(%v)",
+				n, err)
+		}
+	}
+	if l.buf.Len() != sampleBodyLimit || !l.truncated {
+		t.Errorf("expected %v bytes to be kept, but got %v(truncated: %v)",
+			sampleBodyLimit, l.buf.Len(), l.truncated)
+	}
+}
+
+func TestResponseSamplerWriteTo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "samples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := NewResponseSampler(1)
+	sampleResponse(s, 404, "not here")
+	s.Error(errors.New("connection refused"))
+	dir = filepath.Join(dir, "nested")
+	if err = s.WriteTo(dir); err != nil {
+		t.Fatal(err)
+	}
+	response, err := ioutil.ReadFile(filepath.Join(dir, "status-404-1.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{"404 Not Found\n", "X-Status: not here",
+		"\n\nnot here"} {
+		if !strings.Contains(string(response), part) {
+			t.Errorf("expected %q in %q", part, response)
+		}
+	}
+	e, err := ioutil.ReadFile(filepath.Join(dir, "error-1-1.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(e) != "Error: connection refused\n" {
+		t.Errorf("unexpected error sample %q", e)
+	}
+}
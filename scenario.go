@@ -0,0 +1,358 @@
+package bombardier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+)
+
+// ScenarioClient is implemented by clients, that are able to send
+// requests described by scenario steps.
+type ScenarioClient interface {
+	DoRequest(req *StepRequest) (
+		resp StepResponse, usTaken uint64, err error,
+	)
+}
+
+// StepRequest is a request with all the variables expanded.
+type StepRequest struct {
+	method  string
+	url     *url.URL
+	headers []Header
+	body    string
+}
+
+// StepResponse contains parts of the response values can be
+// extracted from.
+type StepResponse struct {
+	code   int
+	header http.Header
+	body   []byte
+}
+
+// Scenario is an ordered list of requests performed by a worker
+// during each iteration. Values extracted from responses are kept
+// in variables, which can be referenced as ${name} in URLs, headers
+// and bodies of the subsequent steps. Variables don't outlive the
+// iteration.
+type Scenario struct {
+	base  *url.URL
+	steps []*ScenarioStep
+
+	completed, aborted uint64
+	latencies          *uhist.Histogram
+}
+
+// ScenarioStep is a single request of the scenario.
+type ScenarioStep struct {
+	name, method, url string
+	headers           []Header
+	body              string
+	extractors        []extractor
+
+	count, errors uint64
+	latencies     *uhist.Histogram
+}
+
+type extractor struct {
+	name   string
+	json   []string
+	regex  *regexp.Regexp
+	header string
+}
+
+type scenarioFile struct {
+	Steps []struct {
+		Name    string   `json:"name"`
+		Method  string   `json:"method"`
+		URL     string   `json:"url"`
+		Headers []string `json:"headers"`
+		Body    string   `json:"body"`
+		Extract []struct {
+			Var    string `json:"var"`
+			JSON   string `json:"json"`
+			Regex  string `json:"regex"`
+			Header string `json:"header"`
+		} `json:"extract"`
+	} `json:"steps"`
+}
+
+// LoadScenario reads scenario from the JSON file at path. URLs of the
+// steps are resolved relative to baseURL and have to point to the same
+// host.
+func LoadScenario(path, baseURL string) (*Scenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f scenarioFile
+	if err = json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("scenario: %v", err)
+	}
+	if len(f.Steps) == 0 {
+		return nil, errEmptyScenario
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	s := &Scenario{base: base, latencies: uhist.Default()}
+	for i, fs := range f.Steps {
+		step := &ScenarioStep{
+			name:      fs.Name,
+			method:    fs.Method,
+			url:       fs.URL,
+			body:      fs.Body,
+			latencies: uhist.Default(),
+		}
+		if step.method == "" {
+			step.method = "GET"
+		}
+		if step.name == "" {
+			step.name = step.method + " " + step.url
+		}
+		if !AllowedHTTPMethod(step.method) {
+			return nil, fmt.Errorf("scenario step %v: %v",
+				i+1, &InvalidHTTPMethodError{method: step.method})
+		}
+		if !CanHaveBody(step.method) && step.body != "" {
+			return nil, fmt.Errorf("scenario step %v: %v",
+				i+1, errBodyNotAllowed)
+		}
+		if step.url == "" {
+			return nil, fmt.Errorf("scenario step %v: no URL", i+1)
+		}
+		headers := new(HeadersList)
+		for _, h := range fs.Headers {
+			if err = headers.Set(h); err != nil {
+				return nil, fmt.Errorf("scenario step %v: %v", i+1, err)
+			}
+		}
+		step.headers = *headers
+		for _, fe := range fs.Extract {
+			e, eerr := newExtractor(
+				fe.Var, fe.JSON, fe.Regex, fe.Header,
+			)
+			if eerr != nil {
+				return nil, fmt.Errorf("scenario step %v: %v", i+1, eerr)
+			}
+			step.extractors = append(step.extractors, e)
+		}
+		s.steps = append(s.steps, step)
+	}
+	return s, nil
+}
+
+func newExtractor(name, jsonPath, regex, header string) (extractor, error) {
+	e := extractor{name: name, header: header}
+	if name == "" {
+		return e, errNoExtractorVariable
+	}
+	sources := 0
+	for _, s := range []string{jsonPath, regex, header} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return e, errInvalidExtractor
+	}
+	if jsonPath != "" {
+		path := strings.TrimPrefix(strings.TrimPrefix(jsonPath, "$"), ".")
+		if path != "" {
+			e.json = strings.Split(path, ".")
+		} else {
+			e.json = []string{}
+		}
+	}
+	if regex != "" {
+		re, err := regexp.Compile(regex)
+		if err != nil {
+			return e, err
+		}
+		e.regex = re
+	}
+	return e, nil
+}
+
+// Extract returns value extracted from the response, if any.
+func (e extractor) Extract(resp StepResponse) (string, bool) {
+	switch {
+	case e.header != "":
+		values, ok := resp.header[http.CanonicalHeaderKey(e.header)]
+		if !ok || len(values) == 0 {
+			return "", false
+		}
+		return values[0], true
+	case e.regex != nil:
+		m := e.regex.FindSubmatch(resp.body)
+		if m == nil {
+			return "", false
+		}
+		// Use the first group if there is one and the whole match
+		// otherwise
+		if len(m) > 1 {
+			return string(m[1]), true
+		}
+		return string(m[0]), true
+	}
+	return extractJSON(resp.body, e.json)
+}
+
+// extractJSON walks the document following path, where object keys
+// and array indices are separated by dots (e.g. data.items.0.id).
+func extractJSON(body []byte, path []string) (string, bool) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return "", false
+	}
+	for _, key := range path {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = node[key]; !ok {
+				return "", false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", false
+			}
+			v = node[i]
+		default:
+			return "", false
+		}
+	}
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	}
+	res, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	return string(res), true
+}
+
+var scenarioVarRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandVars replaces ${name} references in s with values of the
+// variables.
+func expandVars(s string, vars map[string]string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var err error
+	res := scenarioVarRe.ReplaceAllStringFunc(s, func(ref string) string {
+		name := ref[2 : len(ref)-1]
+		v, ok := vars[name]
+		if !ok && err == nil {
+			err = fmt.Errorf("undefined variable %q", name)
+		}
+		return v
+	})
+	return res, err
+}
+
+// request builds request for the step using current values of the
+// variables.
+func (s *Scenario) request(
+	step *ScenarioStep, vars map[string]string,
+) (*StepRequest, error) {
+	rawURL, err := expandVars(step.url, vars)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	u := s.base.ResolveReference(ref)
+	if u.Scheme != s.base.Scheme || u.Host != s.base.Host {
+		return nil, errScenarioOtherHost
+	}
+	req := &StepRequest{method: step.method, url: u}
+	for _, h := range step.headers {
+		v, verr := expandVars(h.value, vars)
+		if verr != nil {
+			return nil, verr
+		}
+		req.headers = append(req.headers, Header{h.key, v})
+	}
+	if req.body, err = expandVars(step.body, vars); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Run performs single iteration of the scenario, reporting results of
+// every request to record. Iteration is aborted after the first step
+// that failed or didn't yield all the values it was supposed to. pause
+// is called between the steps and stops the iteration, without counting
+// it, when it returns false.
+func (s *Scenario) Run(
+	client ScenarioClient,
+	record func(code int, usTaken uint64, err error),
+	pause func() bool,
+) {
+	vars := make(map[string]string)
+	start := time.Now()
+	for i, step := range s.steps {
+		if i != 0 && !pause() {
+			return
+		}
+		if !s.runStep(client, step, vars, record) {
+			atomic.AddUint64(&s.aborted, 1)
+			return
+		}
+	}
+	s.latencies.Increment(uint64(time.Since(start).Nanoseconds() / 1000))
+	atomic.AddUint64(&s.completed, 1)
+}
+
+func (s *Scenario) runStep(
+	client ScenarioClient, step *ScenarioStep, vars map[string]string,
+	record func(code int, usTaken uint64, err error),
+) bool {
+	atomic.AddUint64(&step.count, 1)
+	req, err := s.request(step, vars)
+	if err != nil {
+		atomic.AddUint64(&step.errors, 1)
+		record(-1, 0, fmt.Errorf("%v: %v", step.name, err))
+		return false
+	}
+	resp, usTaken, err := client.DoRequest(req)
+	step.latencies.Increment(usTaken)
+	if err != nil {
+		atomic.AddUint64(&step.errors, 1)
+		record(-1, usTaken, err)
+		return false
+	}
+	for _, e := range step.extractors {
+		v, ok := e.Extract(resp)
+		if !ok {
+			atomic.AddUint64(&step.errors, 1)
+			record(resp.code, usTaken, &assertionError{fmt.Sprintf(
+				"%v: failed to extract %v", step.name, e.name,
+			)})
+			return false
+		}
+		vars[e.name] = v
+	}
+	record(resp.code, usTaken, nil)
+	return true
+}
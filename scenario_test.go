@@ -0,0 +1,170 @@
+package bombardier
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandVars(t *testing.T) {
+	vars := map[string]string{"id": "42", "token": "abc"}
+	expectations := []struct {
+		in, out string
+		err     bool
+	}{
+		{"/users", "/users", false},
+		{"/users/${id}", "/users/42", false},
+		{"Bearer ${token}:${id}", "Bearer abc:42", false},
+		{"${", "${", false},
+		{"/users/${missing}", "", true},
+	}
+	for _, e := range expectations {
+		out, err := expandVars(e.in, vars)
+		if e.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", e.in)
+			}
+			continue
+		}
+		if err != nil || out != e.out {
+			t.Errorf("%q: expected %q, but got %q, %v", e.in, e.out, out, err)
+		}
+	}
+}
+
+func TestExtractors(t *testing.T) {
+	resp := StepResponse{
+		code:   http.StatusOK,
+		header: http.Header{"X-Session": {"s1"}},
+		body: []byte(`{"token":"abc","user":{"id":42,` +
+			`"roles":["admin","dev"],"active":true}}`),
+	}
+	expectations := []struct {
+		jsonPath, regex, header string
+		out                     string
+		ok                      bool
+	}{
+		{"token", "", "", "abc", true},
+		{"$.user.id", "", "", "42", true},
+		{"user.roles.1", "", "", "dev", true},
+		{"user.roles", "", "", `["admin","dev"]`, true},
+		{"user.active", "", "", "true", true},
+		{"user.roles.2", "", "", "", false},
+		{"user.name", "", "", "", false},
+		{"token.length", "", "", "", false},
+		{"", `"id":(\d+)`, "", "42", true},
+		{"", `admin`, "", "admin", true},
+		{"", `root`, "", "", false},
+		{"", "", "x-session", "s1", true},
+		{"", "", "X-Missing", "", false},
+	}
+	for _, e := range expectations {
+		ex, err := newExtractor("v", e.jsonPath, e.regex, e.header)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		out, ok := ex.Extract(resp)
+		if ok != e.ok || out != e.out {
+			t.Errorf("%+v: expected %q, %v, but got %q, %v",
+				e, e.out, e.ok, out, ok)
+		}
+	}
+}
+
+func TestNewExtractorErrors(t *testing.T) {
+	if _, err := newExtractor("", "token", "", ""); err != errNoExtractorVariable {
+		t.Errorf("expected %v, but got %v", errNoExtractorVariable, err)
+	}
+	if _, err := newExtractor("v", "", "", ""); err != errInvalidExtractor {
+		t.Errorf("expected %v, but got %v", errInvalidExtractor, err)
+	}
+	if _, err := newExtractor("v", "a", "b", ""); err != errInvalidExtractor {
+		t.Errorf("expected %v, but got %v", errInvalidExtractor, err)
+	}
+	if _, err := newExtractor("v", "", "(", ""); err == nil {
+		t.Error("expected invalid regex to be rejected")
+	}
+}
+
+func writeTestScenario(t *testing.T, dir, content string) string {
+	path := filepath.Join(dir, "scenario.json")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadScenario(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bombardier-scenario")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	expectations := []struct {
+		content string
+		valid   bool
+	}{
+		{`{"steps":[{"url":"/"}]}`, true},
+		{`{"steps":[{"name":"login","method":"POST","url":"/login",` +
+			`"headers":["Content-Type: application/json"],"body":"{}",` +
+			`"extract":[{"var":"token","json":"token"}]}]}`, true},
+		{`{"steps":[]}`, false},
+		{`{"steps":[{"method":"GET"}]}`, false},
+		{`{"steps":[{"method":"BREW","url":"/"}]}`, false},
+		{`{"steps":[{"url":"/","body":"data"}]}`, false},
+		{`{"steps":[{"url":"/","headers":["invalid"]}]}`, false},
+		{`{"steps":[{"url":"/","extract":[{"var":"v"}]}]}`, false},
+		{`{"steps":`, false},
+	}
+	for _, e := range expectations {
+		path := writeTestScenario(t, dir, e.content)
+		s, err := LoadScenario(path, "http://localhost:8080")
+		if e.valid && (err != nil || s == nil) {
+			t.Errorf("%v: unexpected error %v", e.content, err)
+		}
+		if !e.valid && err == nil {
+			t.Errorf("%v: expected an error", e.content)
+		}
+	}
+	if _, err := LoadScenario(
+		filepath.Join(dir, "missing.json"), "http://localhost:8080",
+	); err == nil {
+		t.Error("expected missing file to be reported")
+	}
+}
+
+func TestScenarioRequest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bombardier-scenario")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := writeTestScenario(t, dir, `{"steps":[
+		{"method":"PUT","url":"/users/${id}?v=1",
+		 "headers":["Authorization: Bearer ${token}"],
+		 "body":"{\"id\":${id}}"},
+		{"url":"http://example.com/"}
+	]}`)
+	s, err := LoadScenario(path, "http://localhost:8080/api/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]string{"id": "42", "token": "abc"}
+	req, err := s.request(s.steps[0], vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.method != "PUT" ||
+		req.url.String() != "http://localhost:8080/users/42?v=1" ||
+		len(req.headers) != 1 ||
+		req.headers[0] != (Header{"Authorization", "Bearer abc"}) ||
+		req.body != `{"id":42}` {
+		t.Errorf("unexpected request: %+v", req)
+	}
+	if _, err := s.request(s.steps[1], vars); err != errScenarioOtherHost {
+		t.Errorf("expected %v, but got %v", errScenarioOtherHost, err)
+	}
+}
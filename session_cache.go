@@ -0,0 +1,82 @@
+package bombardier
+
+import (
+	"crypto/tls"
+	"sync"
+)
+
+const (
+	tlsResumptionOff     = "off"
+	tlsResumptionTickets = "tickets"
+	tlsResumptionCache   = "cache"
+)
+
+// NewClientSessionCache returns session cache that implements
+// requested resumption mode or nil if resumption is disabled. In
+// "tickets" mode every ticket is used at most once, as recommended
+// for TLS 1.3, so that each resumption consumes a ticket issued by
+// the server earlier. In "cache" mode the latest session for each
+// server is shared by all connections.
+func NewClientSessionCache(
+	mode string, capacity int,
+) tls.ClientSessionCache {
+	switch mode {
+	case tlsResumptionTickets:
+		return NewSingleUseSessionCache(capacity)
+	case tlsResumptionCache:
+		return tls.NewLRUClientSessionCache(0)
+	}
+	return nil
+}
+
+// SingleUseSessionCache is a tls.ClientSessionCache, that hands out
+// every stored session only once. It keeps up to capacity sessions
+// per server, dropping the oldest ones when it's full.
+type SingleUseSessionCache struct {
+	mu       sync.Mutex
+	capacity int
+	sessions map[string][]*tls.ClientSessionState
+}
+
+func NewSingleUseSessionCache(capacity int) *SingleUseSessionCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &SingleUseSessionCache{
+		capacity: capacity,
+		sessions: make(map[string][]*tls.ClientSessionState),
+	}
+}
+
+func (s *SingleUseSessionCache) Get(
+	sessionKey string,
+) (*tls.ClientSessionState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sessions := s.sessions[sessionKey]
+	if len(sessions) == 0 {
+		return nil, false
+	}
+	cs := sessions[len(sessions)-1]
+	s.sessions[sessionKey] = sessions[:len(sessions)-1]
+	return cs, true
+}
+
+func (s *SingleUseSessionCache) Put(
+	sessionKey string, cs *tls.ClientSessionState,
+) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// nil session means that the server rejected it, so that all the
+	// sessions stored for this server are likely to be stale as well
+	if cs == nil {
+		delete(s.sessions, sessionKey)
+		return
+	}
+	sessions := s.sessions[sessionKey]
+	if len(sessions) >= s.capacity {
+		copy(sessions, sessions[1:])
+		sessions = sessions[:len(sessions)-1]
+	}
+	s.sessions[sessionKey] = append(sessions, cs)
+}
@@ -0,0 +1,48 @@
+package bombardier
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestSingleUseSessionCache(t *testing.T) {
+	c := NewSingleUseSessionCache(2)
+	if _, ok := c.Get("a"); ok {
+		t.Error("empty cache returned a session")
+	}
+	s1, s2, s3 := new(tls.ClientSessionState),
+		new(tls.ClientSessionState), new(tls.ClientSessionState)
+	c.Put("a", s1)
+	c.Put("a", s2)
+	c.Put("a", s3)
+	c.Put("b", s1)
+	for _, exp := range []*tls.ClientSessionState{s3, s2} {
+		if cs, ok := c.Get("a"); !ok || cs != exp {
+			t.Errorf("expected %p, but got %p", exp, cs)
+		}
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("session was returned more than once")
+	}
+	c.Put("b", nil)
+	if _, ok := c.Get("b"); ok {
+		t.Error("sessions weren't evicted")
+	}
+}
+
+func TestNewClientSessionCache(t *testing.T) {
+	expectations := []struct {
+		mode  string
+		isNil bool
+	}{
+		{"", true},
+		{tlsResumptionOff, true},
+		{tlsResumptionTickets, false},
+		{tlsResumptionCache, false},
+	}
+	for _, e := range expectations {
+		if c := NewClientSessionCache(e.mode, 10); (c == nil) != e.isNil {
+			t.Errorf("%q: unexpected cache %v", e.mode, c)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package bombardier
+
+import (
+	"sync"
+	"sync/atomic"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+)
+
+// Status codes outside of this range are counted as others.
+const (
+	minStatusCode = 100
+	maxStatusCode = 599
+)
+
+// StatusCodes counts responses with each of the status codes and,
+// optionally, keeps their latencies.
+type StatusCodes struct {
+	counts [maxStatusCode - minStatusCode + 1]uint64
+
+	withLatencies bool
+	latencies     sync.Map
+}
+
+func NewStatusCodes(withLatencies bool) *StatusCodes {
+	return &StatusCodes{withLatencies: withLatencies}
+}
+
+// Add counts response with the given status code that took usTaken.
+func (s *StatusCodes) Add(code int, usTaken uint64) {
+	if code < minStatusCode || code > maxStatusCode {
+		return
+	}
+	atomic.AddUint64(&s.counts[code-minStatusCode], 1)
+	if !s.withLatencies {
+		return
+	}
+	h, ok := s.latencies.Load(code)
+	if !ok {
+		// Histograms are large, so they are created for the codes
+		// actually seen
+		h, _ = s.latencies.LoadOrStore(code, uhist.Default())
+	}
+	h.(*uhist.Histogram).Increment(usTaken)
+}
+
+// Count returns number of responses with the given status code.
+func (s *StatusCodes) Count(code int) uint64 {
+	if code < minStatusCode || code > maxStatusCode {
+		return 0
+	}
+	return atomic.LoadUint64(&s.counts[code-minStatusCode])
+}
+
+// Latencies returns latencies of responses with the given status code
+// or nil if they aren't kept.
+func (s *StatusCodes) Latencies(code int) *uhist.Histogram {
+	h, ok := s.latencies.Load(code)
+	if !ok {
+		return nil
+	}
+	return h.(*uhist.Histogram)
+}
+
+// Seen returns status codes of the responses received in ascending
+// order.
+func (s *StatusCodes) Seen() []int {
+	var codes []int
+	for i := range s.counts {
+		if atomic.LoadUint64(&s.counts[i]) > 0 {
+			codes = append(codes, i+minStatusCode)
+		}
+	}
+	return codes
+}
@@ -0,0 +1,54 @@
+package bombardier
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestStatusCodesCount(t *testing.T) {
+	s := NewStatusCodes(false)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Add(429, 10)
+				s.Add(200, 10)
+			}
+		}()
+	}
+	wg.Wait()
+	s.Add(404, 10)
+	s.Add(-1, 10)
+	s.Add(600, 10)
+	if c := s.Count(429); c != 400 {
+		t.Errorf("expected 400 responses with 429, but got %v", c)
+	}
+	if c := s.Count(-1); c != 0 {
+		t.Errorf("expected no responses with -1, but got %v", c)
+	}
+	if codes := s.Seen(); !reflect.DeepEqual(codes, []int{200, 404, 429}) {
+		t.Errorf("unexpected status codes %v", codes)
+	}
+	if h := s.Latencies(200); h != nil {
+		t.Errorf("expected no latencies, but got %v", h)
+	}
+}
+
+func TestStatusCodesLatencies(t *testing.T) {
+	s := NewStatusCodes(true)
+	s.Add(200, 10)
+	s.Add(200, 30)
+	s.Add(500, 1000)
+	if h := s.Latencies(200); h == nil || h.Count() != 2 || h.Get(30) != 1 {
+		t.Errorf("unexpected latencies of 200: %v", h)
+	}
+	if h := s.Latencies(500); h == nil || h.Get(1000) != 1 {
+		t.Errorf("unexpected latencies of 500: %v", h)
+	}
+	if h := s.Latencies(404); h != nil {
+		t.Errorf("expected no latencies of 404, but got %v", h)
+	}
+}
@@ -0,0 +1,45 @@
+package bombardier
+
+import (
+	"sync/atomic"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+)
+
+// StreamStats keeps track of how many HTTP/2 streams were in flight over
+// each of the connections as requests were sent.
+type StreamStats struct {
+	inFlight *uhist.Histogram
+}
+
+func NewStreamStats() *StreamStats {
+	return &StreamStats{inFlight: uhist.Default()}
+}
+
+// Conn returns counter of streams in flight over a single connection.
+func (s *StreamStats) Conn() *ConnStreams {
+	return &ConnStreams{stats: s}
+}
+
+// InFlight returns average and maximum number of streams in flight per
+// connection.
+func (s *StreamStats) InFlight() (avg float64, max uint64) {
+	return avgAndMax(s.inFlight)
+}
+
+// ConnStreams counts streams in flight over one of the connections.
+type ConnStreams struct {
+	stats    *StreamStats
+	inFlight int64
+}
+
+// Begin records stream about to be opened.
+func (c *ConnStreams) Begin() {
+	inFlight := atomic.AddInt64(&c.inFlight, 1)
+	c.stats.inFlight.Increment(uint64(inFlight))
+}
+
+// End records stream that was closed.
+func (c *ConnStreams) End() {
+	atomic.AddInt64(&c.inFlight, -1)
+}
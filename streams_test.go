@@ -0,0 +1,34 @@
+package bombardier
+
+import (
+	"testing"
+)
+
+func TestStreamStats(t *testing.T) {
+	s := NewStreamStats()
+	if avg, max := s.InFlight(); avg != 0 || max != 0 {
+		t.Errorf("expected no streams, but got %v avg and %v max", avg, max)
+	}
+	first, second := s.Conn(), s.Conn()
+	// 1, 2, 3 streams over the first connection and 1 over the second
+	for i := 0; i < 3; i++ {
+		first.Begin()
+	}
+	second.Begin()
+	if avg, max := s.InFlight(); avg != 1.75 || max != 3 {
+		t.Errorf("expected 1.75 avg and 3 max, but got %v and %v", avg, max)
+	}
+	for i := 0; i < 3; i++ {
+		first.End()
+	}
+	second.End()
+	if first.inFlight != 0 || second.inFlight != 0 {
+		t.Errorf("expected no streams in flight, but got %v and %v",
+			first.inFlight, second.inFlight)
+	}
+	// Streams are counted per connection
+	first.Begin()
+	if _, max := s.InFlight(); max != 3 {
+		t.Errorf("expected 3 max, but got %v", max)
+	}
+}
@@ -42,7 +42,7 @@ const (
 {{ else }}
 	{{- print "  There wasn't enough data to compute statistics for requests." }}
 {{ end }}
-{{ with .Result.LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
+{{ with .Result.SuccessLatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
 	{{- printf "  %-10v %10v %10v %10v" "Latency" (FormatTimeUs .Mean) (FormatTimeUs .Stddev) (FormatTimeUs .Max) }}
 	{{- if WithLatencies }}
   		{{- "\n  Latency Distribution" }}
@@ -51,16 +51,118 @@ const (
 		{{ end -}}
 	{{ end }}
 {{ else }}
-	{{- print "  There wasn't enough data to compute statistics for latencies." }}
+	{{- print "  There wasn't enough data to compute statistics for latencies of successful requests." }}
 {{ end -}}
+{{ with .Result.FailedLatencies }}
+	{{- range . }}
+		{{- $outcome := .Outcome }}
+		{{- with .LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
+			{{- printf "  %-10v %10v %10v %10v\n" (printf "Lat. %v" $outcome) (FormatTimeUs .Mean) (FormatTimeUs .Stddev) (FormatTimeUs .Max) }}
+		{{- end }}
+	{{- end }}
+	{{- with $.Result.LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
+		{{- printf "  %-10v %10v %10v %10v\n" "Lat. all" (FormatTimeUs .Mean) (FormatTimeUs .Stddev) (FormatTimeUs .Max) }}
+	{{- end }}
+{{- end -}}
+{{ with .Result.ProxyLatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
+	{{- printf "  %-10v %10v %10v %10v\n" "Proxy conn" (FormatTimeUs .Mean) (FormatTimeUs .Stddev) (FormatTimeUs .Max) }}
+{{- end -}}
 {{ with .Result -}}
 {{ "  HTTP codes:" }}
 {{ printf "    1xx - %v, 2xx - %v, 3xx - %v, 4xx - %v, 5xx - %v" .Req1XX .Req2XX .Req3XX .Req4XX .Req5XX }}
 	{{- printf "\n    others - %v" .Others }}
+	{{- range .StatusCodes }}
+		{{- printf "\n    %v - %v" .Code .Count }}
+		{{- with .LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
+			{{- printf ", latency %v avg, %v max" (FormatTimeUs .Mean) (FormatTimeUs .Max) }}
+		{{- end }}
+	{{- end }}
+	{{- with .Addresses }}
+		{{- "\n  Addresses:"}}
+		{{- range . }}
+			{{- printf "\n    %-21v - %v" .Address .Count }}
+			{{- with .LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
+				{{- printf ", latency %v avg, %v max" (FormatTimeUs .Mean) (FormatTimeUs .Max) }}
+			{{- end }}
+		{{- end -}}
+	{{ end -}}
+	{{- with .TLS }}
+		{{- "\n  TLS:"}}
+		{{- range . }}
+			{{- printf "\n    %v %v - %v" .Version .CipherSuite .Count }}
+		{{- end -}}
+	{{ end -}}
+	{{- with .TLSHandshakes }}
+		{{- printf "\n  TLS handshakes:\n    full - %v" .Full }}
+		{{- with .FullLatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
+			{{- printf ", latency %v avg, %v max" (FormatTimeUs .Mean) (FormatTimeUs .Max) }}
+		{{- end }}
+		{{- printf "\n    resumed - %v" .Resumed }}
+		{{- with .ResumedLatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
+			{{- printf ", latency %v avg, %v max" (FormatTimeUs .Mean) (FormatTimeUs .Max) }}
+		{{- end }}
+	{{- end -}}
+	{{- with .ClientIdentities }}
+		{{- "\n  Client identities:"}}
+		{{- range . }}
+			{{- printf "\n    %v - %v" .Identity .Count }}
+		{{- end -}}
+	{{ end -}}
+	{{- with .Pipeline }}
+		{{- printf "\n  Pipeline:\n    depth - %.2f avg, %v max" .AvgDepth .MaxDepth }}
+		{{- printf "\n    overflows - %v, stopped connections - %v" .Overflows .Stopped }}
+	{{- end -}}
+	{{- with .Connections }}
+		{{- printf "\n  Connections:\n    opened - %v, closed by server - %v, reused - %v" .Opened .ClosedByServer .Reused }}
+	{{- end -}}
+	{{- with .Streams }}
+		{{- printf "\n  Streams:\n    in flight per connection - %.2f avg, %v max" .AvgInFlight .MaxInFlight }}
+	{{- end -}}
+	{{- with .Decompression }}
+		{{- printf "\n  Decompression:\n    read - %v bytes, compressed - %v bytes, decompressed - %v bytes" $.Result.BytesRead .Compressed .Decompressed }}
+		{{- printf "\n    errors - %v" .Errors }}
+	{{- end -}}
+	{{- with .Scenario }}
+		{{- printf "\n  Scenario:\n    iterations - %v completed, %v aborted" .Completed .Aborted }}
+		{{- with .LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
+			{{- printf ", latency %v avg, %v max" (FormatTimeUs .Mean) (FormatTimeUs .Max) }}
+		{{- end }}
+		{{- range .Steps }}
+			{{- printf "\n    %v - %v, %v errors" .Name .Count .Errors }}
+			{{- with .LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
+				{{- printf ", latency %v avg, %v max" (FormatTimeUs .Mean) (FormatTimeUs .Max) }}
+			{{- end }}
+		{{- end -}}
+	{{ end -}}
+	{{- with .Samples }}
+		{{- "\n  Response samples:"}}
+		{{- range . }}
+			{{- if .Error }}
+				{{- printf "\n    %v - %v kept of %v" .Error (len .Samples) .Seen }}
+			{{- else }}
+				{{- printf "\n    %v - %v kept of %v" .Status (len .Samples) .Seen }}
+			{{- end }}
+		{{- end }}
+		{{- with $.Result.SamplesDir }}
+			{{- printf "\n    written to %v" . }}
+		{{- end }}
+	{{- end -}}
+	{{- with .TraceLog }}
+		{{- printf "\n  Trace log:\n    written - %v, dropped - %v" .Written .Dropped }}
+	{{- end -}}
 	{{- with .Errors }}
 		{{- "\n  Errors:"}}
 		{{- range . }}
-			{{- printf "\n    %10v - %v" .Error .Count }}
+			{{- if .Class }}
+				{{- printf "\n    %10v - %v, e.g. %v" .Class .Count .Error }}
+			{{- else }}
+				{{- printf "\n    %10v - %v" .Error .Count }}
+			{{- end }}
+			{{- printf "\n      first at %.2fs, last at %.2fs" .First.Seconds .Last.Seconds }}
+			{{- with .PerSecond }}
+				{{- "\n      per second:" }}
+				{{- range . }}{{ printf " %v" . }}{{ end }}
+			{{- end }}
 		{{- end -}}
 	{{ end -}}
 {{ end }}
@@ -77,6 +179,44 @@ const (
 
 ,"method":"{{ .Method }}","url":{{ .URL | printf "%q" }}
 
+{{- with .UnixSocket -}}
+,"unixSocket":{{ . | printf "%q" }}
+{{- end -}}
+
+{{- with .ConnectTo -}}
+,"connectTo":[
+{{- range $index, $entry := . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{{ $entry | printf "%q" }}
+{{- end -}}
+]
+{{- end -}}
+{{- with .Resolve -}}
+,"resolve":[
+{{- range $index, $entry := . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{{ $entry | printf "%q" }}
+{{- end -}}
+]
+{{- end -}}
+{{- if .SpreadAddrs -}}
+,"spreadAddrs":true
+{{- end -}}
+{{- with .LocalAddrs -}}
+,"localAddrs":[
+{{- range $index, $addr := . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{{ $addr | printf "%q" }}
+{{- end -}}
+]
+{{- end -}}
+{{- with .IPVersion -}}
+,"ipVersion":{{ . }}
+{{- end -}}
+{{- with .Proxy -}}
+,"proxy":{{ . | printf "%q" }}
+{{- end -}}
+
 {{- with .Headers -}}
 ,"headers":[
 {{- range $index, $header :=  . -}}
@@ -88,6 +228,13 @@ const (
 
 {{- if .BodyFilePath -}}
 ,"bodyFilePath":{{ .BodyFilePath | printf "%q" }}
+{{- else if .Form -}}
+,"form":[
+{{- range $index, $field := .Form -}}
+{{- if ne $index 0 -}},{{- end -}}
+{{ $field | printf "%q" }}
+{{- end -}}
+]
 {{- else -}}
 ,"body":{{ .Body | printf "%q" }}
 {{- end -}}
@@ -98,6 +245,96 @@ const (
 {{- if .KeyPath -}}
 ,"keyPath":{{ .KeyPath | printf "%q" }}
 {{- end -}}
+{{- with .CACertPath -}}
+,"caCertPath":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .SNI -}}
+,"sni":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .TLSMinVersion -}}
+,"tlsMinVersion":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .TLSMaxVersion -}}
+,"tlsMaxVersion":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .Ciphers -}}
+,"ciphers":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .Curves -}}
+,"curves":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .ALPN -}}
+,"alpn":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .TLSResumption -}}
+,"tlsResumption":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .TLSKeyLog -}}
+,"tlsKeyLog":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .Auth -}}
+,"auth":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .OAuth2TokenURL -}}
+,"oauth2TokenURL":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .OAuth2Scope -}}
+,"oauth2Scope":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .Cookies -}}
+,"cookies":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .Scenario -}}
+,"scenario":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .ThinkTime -}}
+,"thinkTime":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .Pacing -}}
+,"pacingSeconds":{{ .Seconds }}
+{{- end -}}
+{{- with .Pipeline -}}
+,"pipeline":{{ . }}
+{{- end -}}
+{{- with .MaxConnRequests -}}
+,"maxConnRequests":{{ . }}
+{{- end -}}
+{{- with .MaxConnAge -}}
+,"maxConnAgeSeconds":{{ .Seconds }}
+{{- end -}}
+{{- with .StreamsPerConnection -}}
+,"streamsPerConnection":{{ . }}
+{{- end -}}
+{{- with .BandwidthLimit -}}
+,"bandwidthLimit":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .ConnLatency -}}
+,"connLatencySeconds":{{ .Seconds }}
+{{- end -}}
+{{- with .SampleResponses -}}
+,"sampleResponses":{{ . }}
+{{- end -}}
+{{- with .SampleDir -}}
+,"sampleDir":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .TraceLog -}}
+,"traceLog":{{ . | printf "%q" }}
+{{- end -}}
+{{- if .ErrorHistogram -}}
+,"errorHistogram":true
+{{- end -}}
+{{- if .StatusLatencies -}}
+,"statusLatencies":true
+{{- end -}}
+{{- with .CompressBody -}}
+,"compressBody":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .AcceptEncoding -}}
+,"acceptEncoding":{{ . | printf "%q" }}
+{{- end -}}
+{{- if .Decompress -}}
+,"decompress":true
+{{- end -}}
 
 ,"stream":{{ .Stream }},"timeoutSeconds":{{ .Timeout.Seconds }}
 
@@ -129,11 +366,150 @@ const (
 ,"req5xx":{{ .Req5XX -}}
 ,"others":{{ .Others -}}
 
+{{- with .StatusCodes -}}
+,"statusCodes":[
+{{- range $index, $status := . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{"code":{{ .Code }},"count":{{ .Count }}
+{{- with .LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+,"latency":{"mean":{{ .Mean }},"stddev":{{ .Stddev }},"max":{{ .Max }}}
+{{- end -}}
+}
+{{- end -}}
+]
+{{- end -}}
+
+{{- with .Addresses -}}
+,"addresses":[
+{{- range $index, $addr := . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{"address":{{ .Address | printf "%q" }},"count":{{ .Count }}
+{{- with .LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+,"latency":{"mean":{{ .Mean }},"stddev":{{ .Stddev }},"max":{{ .Max }}}
+{{- end -}}
+}
+{{- end -}}
+]
+{{- end -}}
+
+{{- with .TLS -}}
+,"tls":[
+{{- range $index, $params := . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{"version":{{ .Version | printf "%q" }},"cipherSuite":{{ .CipherSuite | printf "%q" }},"count":{{ .Count }}}
+{{- end -}}
+]
+{{- end -}}
+
+{{- with .TLSHandshakes -}}
+,"tlsHandshakes":{"full":{{ .Full }},"resumed":{{ .Resumed }}
+{{- with .FullLatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+,"fullLatency":{"mean":{{ .Mean }},"stddev":{{ .Stddev }},"max":{{ .Max }}}
+{{- end -}}
+{{- with .ResumedLatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+,"resumedLatency":{"mean":{{ .Mean }},"stddev":{{ .Stddev }},"max":{{ .Max }}}
+{{- end -}}
+}
+{{- end -}}
+
+{{- with .ClientIdentities -}}
+,"clientIdentities":[
+{{- range $index, $identity := . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{"identity":{{ .Identity | printf "%q" }},"count":{{ .Count }}}
+{{- end -}}
+]
+{{- end -}}
+
+{{- with .Pipeline -}}
+,"pipeline":{"avgDepth":{{ .AvgDepth }},"maxDepth":{{ .MaxDepth }},"overflows":{{ .Overflows }},"stopped":{{ .Stopped }}}
+{{- end -}}
+
+{{- with .Connections -}}
+,"connections":{"opened":{{ .Opened }},"closedByServer":{{ .ClosedByServer }},"reused":{{ .Reused }}}
+{{- end -}}
+
+{{- with .Streams -}}
+,"streams":{"avgInFlight":{{ .AvgInFlight }},"maxInFlight":{{ .MaxInFlight }}}
+{{- end -}}
+
+{{- with .Decompression -}}
+,"decompression":{"compressedBytes":{{ .Compressed }},"decompressedBytes":{{ .Decompressed }},"errors":{{ .Errors }}}
+{{- end -}}
+
+{{- with .Scenario -}}
+,"scenario":{"completed":{{ .Completed }},"aborted":{{ .Aborted }}
+{{- with .LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+,"latency":{"mean":{{ .Mean }},"stddev":{{ .Stddev }},"max":{{ .Max }}}
+{{- end -}}
+,"steps":[
+{{- range $index, $step := .Steps -}}
+{{- if ne $index 0 -}},{{- end -}}
+{"name":{{ .Name | printf "%q" }},"count":{{ .Count }},"errors":{{ .Errors }}
+{{- with .LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+,"latency":{"mean":{{ .Mean }},"stddev":{{ .Stddev }},"max":{{ .Max }}}
+{{- end -}}
+}
+{{- end -}}
+]}
+{{- end -}}
+
+{{- with .Samples -}}
+,"samples":[
+{{- range $index, $group := . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{
+{{- if .Error -}}
+"error":{{ .Error | printf "%q" }}
+{{- else -}}
+"status":{{ .Status }}
+{{- end -}}
+,"seen":{{ .Seen }}
+{{- if not $.Result.SamplesDir -}}
+,"responses":[
+{{- range $i, $sample := .Samples -}}
+{{- if ne $i 0 -}},{{- end -}}
+{{- if .Error -}}
+{"error":{{ .Error | printf "%q" }}}
+{{- else -}}
+{"status":{{ .Status }},"headers":[
+{{- range $j, $header := .Headers -}}
+{{- if ne $j 0 -}},{{- end -}}
+{"key":{{ .Key | printf "%q" }},"value":{{ JSONString .Value }}}
+{{- end -}}
+],"body":{{ JSONString .Body }},"truncated":{{ .Truncated }}}
+{{- end -}}
+{{- end -}}
+]
+{{- end -}}
+}
+{{- end -}}
+]
+{{- end -}}
+
+{{- with .SamplesDir -}}
+,"samplesDir":{{ . | printf "%q" }}
+{{- end -}}
+
+{{- with .TraceLog -}}
+,"traceLog":{"written":{{ .Written }},"dropped":{{ .Dropped }}}
+{{- end -}}
+
 {{- with .Errors -}}
 ,"errors":[
 {{- range $index, $error :=  . -}}
 {{- if ne $index 0 -}},{{- end -}}
-{"description":{{ .Error | printf "%q" }},"count":{{ .Count }}}
+{"class":{{ .Class | printf "%q" }},"description":{{ .Error | printf "%q" }},"count":{{ .Count -}}
+,"firstSeenSeconds":{{ .First.Seconds }},"lastSeenSeconds":{{ .Last.Seconds }}
+{{- with .PerSecond -}}
+,"perSecond":[
+{{- range $i, $count := . -}}
+{{- if ne $i 0 -}},{{- end -}}
+{{ $count }}
+{{- end -}}
+]
+{{- end -}}
+}
 {{- end -}}
 ]
 {{- end -}}
@@ -155,6 +531,39 @@ const (
 }
 {{- end -}}
 
+{{- with .SuccessLatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+,"successLatency":{"mean":{{ .Mean -}}
+,"stddev":{{ .Stddev -}}
+,"max":{{ .Max -}}
+
+{{- if WithLatencies -}}
+,"percentiles":{
+{{- range $pc, $lat := .Percentiles }}
+{{- if ne $pc 0.5 -}},{{- end -}}
+{{- printf "\"%2.0f\":%d" (Multiply $pc 100) $lat -}}
+{{- end -}}
+}
+{{- end -}}
+
+}
+{{- end -}}
+
+{{- with .FailedLatencies -}}
+,"failedLatency":{
+{{- range $index, $failed := . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{{- .Outcome | printf "%q" -}}:
+{{- with .LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+{"mean":{{ .Mean }},"stddev":{{ .Stddev }},"max":{{ .Max }}}
+{{- end -}}
+{{- end -}}
+}
+{{- end -}}
+
+{{- with .ProxyLatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+,"proxyConnectLatency":{"mean":{{ .Mean }},"stddev":{{ .Stddev }},"max":{{ .Max }}}
+{{- end -}}
+
 {{- with .RequestsStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
 ,"rps":{"mean":{{ .Mean -}}
 ,"stddev":{{ .Stddev -}}
@@ -0,0 +1,112 @@
+package bombardier
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+)
+
+const (
+	thinkTimeFixed       = "fixed"
+	thinkTimeUniform     = "uniform"
+	thinkTimeNormal      = "normal"
+	thinkTimeExponential = "exponential"
+)
+
+// ThinkTime describes how long workers wait after each request. Fixed
+// delays are given as a plain duration, while random ones are drawn
+// from uniform:MIN,MAX, normal:MEAN,STDDEV or exponential:MEAN
+// distributions.
+type ThinkTime struct {
+	dist string
+	a, b time.Duration
+}
+
+func (t *ThinkTime) String() string {
+	switch t.dist {
+	case thinkTimeFixed:
+		return t.a.String()
+	case thinkTimeUniform, thinkTimeNormal:
+		return t.dist + ":" + t.a.String() + "," + t.b.String()
+	case thinkTimeExponential:
+		return t.dist + ":" + t.a.String()
+	}
+	return ""
+}
+
+func (t *ThinkTime) Set(value string) error {
+	dist, params := thinkTimeFixed, value
+	if i := strings.IndexByte(value, ':'); i != -1 {
+		dist, params = value[:i], value[i+1:]
+		if dist == "exp" {
+			dist = thinkTimeExponential
+		}
+	}
+	var durations []time.Duration
+	for _, p := range strings.Split(params, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(p))
+		if err != nil || d < 0 {
+			return errInvalidThinkTime
+		}
+		durations = append(durations, d)
+	}
+	expected := 1
+	switch dist {
+	case thinkTimeFixed, thinkTimeExponential:
+	case thinkTimeUniform, thinkTimeNormal:
+		expected = 2
+	default:
+		return errInvalidThinkTime
+	}
+	if len(durations) != expected {
+		return errInvalidThinkTime
+	}
+	if dist == thinkTimeUniform && durations[0] > durations[1] {
+		return errInvalidThinkTime
+	}
+	*t = ThinkTime{dist: dist, a: durations[0]}
+	if expected == 2 {
+		t.b = durations[1]
+	}
+	return nil
+}
+
+// Next returns the delay before the next request.
+func (t *ThinkTime) Next(rnd *rand.Rand) time.Duration {
+	var d time.Duration
+	switch t.dist {
+	case thinkTimeFixed:
+		d = t.a
+	case thinkTimeUniform:
+		d = t.a + time.Duration(rnd.Int63n(int64(t.b-t.a)+1))
+	case thinkTimeNormal:
+		d = t.a + time.Duration(rnd.NormFloat64()*float64(t.b))
+	case thinkTimeExponential:
+		d = time.Duration(rnd.ExpFloat64() * float64(t.a))
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// sleep waits for d to pass unless done is closed first and tells
+// whether the test should go on.
+func sleep(d time.Duration, done <-chan struct{}) bool {
+	if d <= 0 {
+		select {
+		case <-done:
+			return false
+		default:
+			return true
+		}
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-done:
+		return false
+	}
+}
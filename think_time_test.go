@@ -0,0 +1,94 @@
+package bombardier
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestThinkTimeParsing(t *testing.T) {
+	expectations := []struct {
+		in, out string
+		err     error
+	}{
+		{"500ms", "500ms", nil},
+		{"uniform:100ms,1s", "uniform:100ms,1s", nil},
+		{"uniform:1s, 1s", "uniform:1s,1s", nil},
+		{"normal:300ms,50ms", "normal:300ms,50ms", nil},
+		{"exponential:200ms", "exponential:200ms", nil},
+		{"exp:200ms", "exponential:200ms", nil},
+		{"", "", errInvalidThinkTime},
+		{"-1s", "", errInvalidThinkTime},
+		{"uniform:1s,100ms", "", errInvalidThinkTime},
+		{"uniform:1s", "", errInvalidThinkTime},
+		{"normal:1s,1s,1s", "", errInvalidThinkTime},
+		{"exponential:1s,2s", "", errInvalidThinkTime},
+		{"pareto:1s", "", errInvalidThinkTime},
+		{"uniform:a,b", "", errInvalidThinkTime},
+	}
+	for _, e := range expectations {
+		var tt ThinkTime
+		err := tt.Set(e.in)
+		if err != e.err {
+			t.Errorf("%q: expected %v, but got %v", e.in, e.err, err)
+			continue
+		}
+		if out := tt.String(); out != e.out {
+			t.Errorf("%q: expected %q, but got %q", e.in, e.out, out)
+		}
+	}
+}
+
+func TestThinkTimeNext(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	const samples = 10000
+	expectations := []struct {
+		spec     string
+		min, max time.Duration
+		mean     time.Duration
+	}{
+		{"100ms", 100 * time.Millisecond, 100 * time.Millisecond,
+			100 * time.Millisecond},
+		{"uniform:100ms,300ms", 100 * time.Millisecond,
+			300 * time.Millisecond, 200 * time.Millisecond},
+		{"normal:200ms,20ms", 0, time.Duration(1<<63 - 1),
+			200 * time.Millisecond},
+		{"exponential:200ms", 0, time.Duration(1<<63 - 1),
+			200 * time.Millisecond},
+	}
+	for _, e := range expectations {
+		var tt ThinkTime
+		if err := tt.Set(e.spec); err != nil {
+			t.Fatal(err)
+		}
+		var sum time.Duration
+		for i := 0; i < samples; i++ {
+			d := tt.Next(rnd)
+			if d < e.min || d > e.max {
+				t.Errorf("%v: %v is out of [%v, %v]", e.spec, d, e.min, e.max)
+				break
+			}
+			sum += d
+		}
+		mean := sum / samples
+		if mean < e.mean*9/10 || mean > e.mean*11/10 {
+			t.Errorf("%v: expected mean around %v, but got %v",
+				e.spec, e.mean, mean)
+		}
+	}
+}
+
+func TestSleep(t *testing.T) {
+	done := make(chan struct{})
+	if !sleep(0, done) || !sleep(time.Millisecond, done) {
+		t.Error("expected sleep to complete")
+	}
+	close(done)
+	start := time.Now()
+	if sleep(time.Hour, done) || sleep(0, done) {
+		t.Error("expected sleep to be interrupted")
+	}
+	if time.Since(start) > time.Second {
+		t.Error("sleep wasn't interrupted promptly")
+	}
+}
@@ -0,0 +1,102 @@
+package bombardier
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+)
+
+var tlsVersionNames = map[uint16]string{
+	tls.VersionTLS10: "1.0",
+	tls.VersionTLS11: "1.1",
+	tls.VersionTLS12: "1.2",
+	tls.VersionTLS13: "1.3",
+}
+
+// TLSVersionName returns human-readable name of TLS version.
+func TLSVersionName(version uint16) string {
+	if name, ok := tlsVersionNames[version]; ok {
+		return "TLS " + name
+	}
+	return fmt.Sprintf("0x%04X", version)
+}
+
+type tlsParams struct {
+	version, cipherSuite uint16
+}
+
+// TLSStats counts established TLS connections by negotiated
+// protocol version and cipher suite and keeps track of time taken by
+// full and resumed handshakes.
+type TLSStats struct {
+	mu sync.RWMutex
+	m  map[tlsParams]*uint64
+
+	fullHandshakes    *uhist.Histogram
+	resumedHandshakes *uhist.Histogram
+}
+
+func NewTLSStats() *TLSStats {
+	ts := new(TLSStats)
+	ts.m = make(map[tlsParams]*uint64)
+	ts.fullHandshakes = uhist.Default()
+	ts.resumedHandshakes = uhist.Default()
+	return ts
+}
+
+func (t *TLSStats) Add(state tls.ConnectionState, usTaken uint64) {
+	if state.DidResume {
+		t.resumedHandshakes.Increment(usTaken)
+	} else {
+		t.fullHandshakes.Increment(usTaken)
+	}
+
+	p := tlsParams{state.Version, state.CipherSuite}
+	t.mu.RLock()
+	c, ok := t.m[p]
+	t.mu.RUnlock()
+	if !ok {
+		t.mu.Lock()
+		c, ok = t.m[p]
+		if !ok {
+			c = new(uint64)
+			t.m[p] = c
+		}
+		t.mu.Unlock()
+	}
+	atomic.AddUint64(c, 1)
+}
+
+type TLSParamsWithCount struct {
+	version, cipherSuite string
+	count                uint64
+}
+
+// ByFrequency returns negotiated parameters sorted by number of
+// connections they were used for.
+func (t *TLSStats) ByFrequency() []TLSParamsWithCount {
+	t.mu.RLock()
+	res := make([]TLSParamsWithCount, 0, len(t.m))
+	for p, c := range t.m {
+		res = append(res, TLSParamsWithCount{
+			version:     TLSVersionName(p.version),
+			cipherSuite: tls.CipherSuiteName(p.cipherSuite),
+			count:       atomic.LoadUint64(c),
+		})
+	}
+	t.mu.RUnlock()
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].count != res[j].count {
+			return res[i].count > res[j].count
+		}
+		if res[i].version != res[j].version {
+			return res[i].version > res[j].version
+		}
+		return res[i].cipherSuite < res[j].cipherSuite
+	})
+	return res
+}
@@ -0,0 +1,168 @@
+package bombardier
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Number of entries waiting to be written, entries that don't fit are
+// dropped rather than slowing the workers down.
+const traceLogBuffer = 8192
+
+// TraceEntry describes a single request and its response. Sizes are
+// those of the headers and bodies, bodies streamed without known
+// length aren't counted.
+type TraceEntry struct {
+	Time      time.Time `json:"time"`
+	Worker    uint64    `json:"worker"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"`
+	Status    int       `json:"status"`
+	LatencyUs uint64    `json:"latencyUs"`
+	BytesIn   int64     `json:"bytesIn"`
+	BytesOut  int64     `json:"bytesOut"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// newTraceEntry returns entry describing request that took usTaken to
+// complete with err.
+func newTraceEntry(
+	method, url string, code int, usTaken uint64, err error,
+) *TraceEntry {
+	e := &TraceEntry{
+		Time:      time.Now().Add(-time.Duration(usTaken) * time.Microsecond),
+		Method:    method,
+		URL:       url,
+		Status:    code,
+		LatencyUs: usTaken,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	return e
+}
+
+// TraceableClient is implemented by clients, that are able to describe
+// each of the requests they send.
+type TraceableClient interface {
+	// WithTrace returns client sharing connections with the original
+	// one, but passing description of each request to trace.
+	WithTrace(trace func(*TraceEntry)) Client
+}
+
+// WithTrace returns copy of c that passes each request to trace or c
+// itself if it can't describe requests.
+func WithTrace(c Client, trace func(*TraceEntry)) Client {
+	if tc, ok := c.(TraceableClient); ok {
+		return tc.WithTrace(trace)
+	}
+	return c
+}
+
+// TraceLog writes entries to a file as newline-delimited JSON in
+// background.
+type TraceLog struct {
+	entries chan TraceEntry
+	done    chan struct{}
+	w       *bufio.Writer
+	file    io.Closer
+	err     error
+
+	written, dropped uint64
+}
+
+func NewTraceLog(path string) (*TraceLog, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return newTraceLog(file), nil
+}
+
+func newTraceLog(w io.WriteCloser) *TraceLog {
+	t := &TraceLog{
+		entries: make(chan TraceEntry, traceLogBuffer),
+		done:    make(chan struct{}),
+		w:       bufio.NewWriter(w),
+		file:    w,
+	}
+	go t.run()
+	return t
+}
+
+// Worker returns function adding entries of the worker with the given
+// id to the log.
+func (t *TraceLog) Worker(id uint64) func(*TraceEntry) {
+	return func(e *TraceEntry) {
+		e.Worker = id
+		t.Add(e)
+	}
+}
+
+// Add queues e to be written without blocking, e is dropped if the
+// queue is full.
+func (t *TraceLog) Add(e *TraceEntry) {
+	select {
+	case t.entries <- *e:
+	default:
+		atomic.AddUint64(&t.dropped, 1)
+	}
+}
+
+func (t *TraceLog) run() {
+	defer close(t.done)
+	enc := json.NewEncoder(t.w)
+	for e := range t.entries {
+		if err := enc.Encode(&e); err != nil {
+			if t.err == nil {
+				t.err = err
+			}
+			atomic.AddUint64(&t.dropped, 1)
+			continue
+		}
+		atomic.AddUint64(&t.written, 1)
+	}
+}
+
+// Close writes the remaining entries and closes the file. No entries
+// may be added afterwards.
+func (t *TraceLog) Close() error {
+	close(t.entries)
+	<-t.done
+	err := t.err
+	if ferr := t.w.Flush(); err == nil {
+		err = ferr
+	}
+	if cerr := t.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Written returns number of entries written.
+func (t *TraceLog) Written() uint64 {
+	return atomic.LoadUint64(&t.written)
+}
+
+// Dropped returns number of entries dropped, because the queue was
+// full or they failed to be written.
+func (t *TraceLog) Dropped() uint64 {
+	return atomic.LoadUint64(&t.dropped)
+}
+
+// headerSize returns size of the headers in HTTP/1.x wire format,
+// including the empty line ending them.
+func headerSize(header http.Header) int64 {
+	size := int64(len("\r\n"))
+	for key, values := range header {
+		for _, value := range values {
+			size += int64(len(key) + len(": ") + len(value) + len("\r\n"))
+		}
+	}
+	return size
+}
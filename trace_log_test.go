@@ -0,0 +1,91 @@
+package bombardier
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type closingBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closingBuffer) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestTraceLogWritesEntries(t *testing.T) {
+	var buf closingBuffer
+	l := newTraceLog(&buf)
+	trace := l.Worker(3)
+	trace(newTraceEntry("GET", "http://localhost/", 200, 1500, nil))
+	trace(newTraceEntry("GET", "http://localhost/", -1, 10, errors.New("boom")))
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !buf.closed {
+		t.Error("expected file to be closed")
+	}
+	if l.Written() != 2 || l.Dropped() != 0 {
+		t.Errorf("expected 2 written and 0 dropped, but got %v and %v",
+			l.Written(), l.Dropped())
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, but got %q", buf.String())
+	}
+	var first, second TraceEntry
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatal(err)
+	}
+	if first.Worker != 3 || first.Status != 200 || first.LatencyUs != 1500 ||
+		first.Error != "" {
+		t.Errorf("unexpected entry %+v", first)
+	}
+	if second.Status != -1 || second.Error != "boom" {
+		t.Errorf("unexpected entry %+v", second)
+	}
+	if strings.Contains(lines[0], `"error"`) {
+		t.Errorf("expected no error in %q", lines[0])
+	}
+}
+
+func TestTraceLogDropsEntriesWhenFull(t *testing.T) {
+	var buf closingBuffer
+	// Nothing is written until the log is running
+	l := &TraceLog{
+		entries: make(chan TraceEntry, 1),
+		done:    make(chan struct{}),
+	}
+	for i := 0; i < 3; i++ {
+		l.Add(newTraceEntry("GET", "http://localhost/", 200, 1, nil))
+	}
+	if l.Dropped() != 2 {
+		t.Errorf("expected 2 dropped, but got %v", l.Dropped())
+	}
+	l.w, l.file = bufio.NewWriter(&buf), &buf
+	go l.run()
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if l.Written() != 1 {
+		t.Errorf("expected 1 written, but got %v", l.Written())
+	}
+}
+
+func TestHeaderSize(t *testing.T) {
+	h := http.Header{"A": {"b", "cd"}}
+	// "A: b\r\n" + "A: cd\r\n" + "\r\n"
+	if size := headerSize(h); size != 15 {
+		t.Errorf("expected 15, but got %v", size)
+	}
+}